@@ -475,7 +475,10 @@ func resolve(rules []Rule) ([]*lexer.TokenType, map[string]*Production, []lexer.
 	var modulators []lexer.Modulator
 	for _, r := range rules {
 		if startsWithUpper(r.Name) {
-			token := lexer.NewTokenType(r.Name, r.Match[0][0])
+			token, err := lexer.NewTokenType(r.Name, r.Match[0][0])
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("token %s: %w", r.Name, err)
+			}
 			tokens = append(tokens, token)
 			tokenMap[r.Name] = token
 			if len(r.Match) > 1 && r.Match[1][0] == "#Ignore" {
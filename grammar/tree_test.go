@@ -1,10 +1,12 @@
 package grammar
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/vikashmadhow/lang-tools/lexer"
+	"github.com/vikashmadhow/lang-tools/regex"
 )
 
 func TestMatch(t *testing.T) {
@@ -146,3 +148,16 @@ func testSimpleGrammar() *Grammar {
 	}
 	return g
 }
+
+func TestGrammarSyntaxError(t *testing.T) {
+	rules := []Rule{
+		{"e", [][]string{{"OPEN", "ID"}}},
+		{"OPEN", [][]string{{"("}}},
+		{"ID", [][]string{{"[_a-zA-Z][_a-zA-Z0-9]*"}}},
+	}
+	_, err := NewGrammar("test_syntax_error", rules)
+	var syntaxErr *regex.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Error("expected a syntax error for token OPEN, got", err)
+	}
+}
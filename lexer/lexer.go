@@ -26,6 +26,8 @@ type Lexer struct {
 
 const minBufferSize = 8
 
+// NewLexer creates a lexer for the token types. The patterns of token types which
+// have not been compiled are compiled leniently; use NewTokenType to validate them.
 func NewLexer(definition ...*TokenType) *Lexer {
 	var matchers []*TokenMatcher
 	for _, d := range definition {
@@ -44,7 +46,7 @@ func NewLexer(definition ...*TokenType) *Lexer {
 func NewLexerFromPatterns(patterns ...string) *Lexer {
 	var tokens []*TokenType
 	for i, r := range patterns {
-		tokens = append(tokens, NewTokenType("p"+strconv.Itoa(i), r))
+		tokens = append(tokens, &TokenType{Id: "p" + strconv.Itoa(i), Pattern: r})
 	}
 	return NewLexer(tokens...)
 }
@@ -96,6 +98,9 @@ func (lexer *Lexer) LexSeq(in io.Reader) iter.Seq2[*Token, error] {
 func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error] {
 	return func(yield func(t *Token, e error) bool) {
 		column, line := 1, 1
+		// the position before the last character, at which errors are reported for the
+		// text preceding a full match which ends with that character
+		prevColumn, prevLine := 1, 1
 		scanner := bufio.NewReader(in)
 
 		var unmatchedText strings.Builder
@@ -144,18 +149,21 @@ func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error
 				if r == utf8.RuneError {
 					break
 				}
+				// a full match is only known when the character following it is read, as
+				// assertions at its end, such as \b and $, depend on that character
+				for _, m := range lexer.matchers {
+					if m.matcher.LastMatch == regex.FullMatch && m.matcher.FullMatchBefore(r) &&
+						m.matcher.FullMatch.Len() > lastFullMatch.Len() {
+						lastFullMatchPosition = matched
+						lastFullMatch = m.matcher.FullMatch
+						lastFullMatchToken = m.def
+					}
+				}
 				matched += n
 				noneMatch := true
 				for _, m := range lexer.matchers {
 					if m.matcher.LastMatch != regex.NoMatch {
 						match := m.matcher.MatchNext(r)
-						if match == regex.FullMatch {
-							if m.matcher.FullMatch.Len() > lastFullMatch.Len() {
-								lastFullMatchPosition = matched
-								lastFullMatch = m.matcher.FullMatch
-								lastFullMatchToken = m.def
-							}
-						}
 						if match != regex.NoMatch {
 							noneMatch = false
 						}
@@ -165,7 +173,7 @@ func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error
 				if lastFullMatchPosition == -1 {
 					unmatchedText.WriteRune(r)
 					if noneMatch {
-						lexer.reset()
+						lexer.reset(r)
 					}
 				} else {
 					if unmatchedText.Len() > 0 {
@@ -180,14 +188,15 @@ func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error
 							unmatched = unmatched[0 : lastFullMatchStart-unmatchedStart]
 						}
 						if len(unmatched) > 0 {
-							t, e := lexer.produceErrorToken(unmatched, !matchUnknown, line, column)
+							t, e := lexer.produceErrorToken(unmatched, !matchUnknown, prevLine, prevColumn)
 							if !yield(t, e) || (e != nil && !matchUnknown) {
 								return
 							}
 							emitted += len(unmatched)
 						}
 						unmatchedText.Reset()
-					} else if noneMatch {
+					}
+					if noneMatch {
 						t := lexer.produceToken(lastFullMatchToken, lastFullMatch.String(), line, column)
 						if !yield(t, nil) {
 							return
@@ -195,13 +204,15 @@ func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error
 						emitted = lastFullMatchPosition
 						matched = lastFullMatchPosition
 
+						last, _ := utf8.DecodeLastRuneInString(lastFullMatch.String())
 						lastFullMatchPosition = -1
 						lastFullMatch.Reset()
 						lastFullMatchToken = nil
 
-						lexer.reset()
+						lexer.reset(last)
 					}
 				}
+				prevColumn, prevLine = column, line
 				if !noneMatch {
 					if r == '\n' {
 						line++
@@ -212,6 +223,25 @@ func (lexer *Lexer) lex(in io.Reader, matchUnknown bool) iter.Seq2[*Token, error
 				}
 			}
 		}
+		// the full matches pending at the end of the input
+		for _, m := range lexer.matchers {
+			if m.matcher.LastMatch == regex.FullMatch && m.matcher.FullMatch.Len() > lastFullMatch.Len() {
+				lastFullMatchPosition = matched
+				lastFullMatch = m.matcher.FullMatch
+				lastFullMatchToken = m.def
+			}
+		}
+		if lastFullMatch.Len() > 0 && unmatchedText.Len() > 0 {
+			// the text before the last full match is not matched
+			unmatched := unmatchedText.String()
+			unmatched = unmatched[:len(unmatched)-(matched-lastFullMatchPosition+lastFullMatch.Len())]
+			if len(unmatched) > 0 {
+				t, e := lexer.produceErrorToken(unmatched, !matchUnknown, prevLine, prevColumn)
+				if !yield(t, e) || (e != nil && !matchUnknown) {
+					return
+				}
+			}
+		}
 		if lastFullMatch.Len() == 0 {
 			if unmatchedText.Len() > 0 {
 				unknown := unmatchedText.String()
@@ -292,25 +322,17 @@ func (lexer *Lexer) errorMessage(noMatch string, line, column int) string {
 			} else {
 				msg.WriteString(", ")
 			}
-			trans := m.matcher.Compiled.Dfa.Trans[m.matcher.State]
 			msg.WriteString(m.def.Id + " (next expected character(s): ")
-			f := true
-			for k := range trans {
-				if f {
-					f = false
-				} else {
-					msg.WriteString(", ")
-				}
-				msg.WriteString(k.String())
-			}
+			msg.WriteString(strings.Join(m.matcher.Expected(), ", "))
 			msg.WriteRune(')')
 		}
 	}
 	return msg.String()
 }
 
-func (lexer *Lexer) reset() {
+// reset prepares all matchers to match a new token following the character prev.
+func (lexer *Lexer) reset(prev rune) {
 	for _, m := range lexer.matchers {
-		m.matcher.Reset()
+		m.matcher.ResetAfter(prev)
 	}
 }
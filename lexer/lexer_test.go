@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/vikashmadhow/lang-tools/regex"
 	"github.com/vikashmadhow/lang-tools/seq"
 )
 
@@ -284,6 +286,85 @@ func TestEndError(t *testing.T) {
 	}
 }
 
+func TestLazyError(t *testing.T) {
+	l := NewLexer(
+		&TokenType{Id: "LET", Pattern: "let", Compiled: regex.MustCompile("let", regex.Lazy(0))},
+		&TokenType{Id: "ID", Pattern: "[a-z]+[0-9]", Compiled: regex.MustCompile("[a-z]+[0-9]", regex.Lazy(0))},
+	)
+	var err error
+	for _, e := range l.LexTextSeq("le") {
+		if e != nil {
+			err = e
+		}
+	}
+	if expected := "LET (next expected character(s): t)"; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("error %v does not contain %q", err, expected)
+	}
+}
+
+func TestWordBoundary(t *testing.T) {
+	l := NewLexer(
+		&TokenType{Id: "FOO", Pattern: "foo"},
+		&TokenType{Id: "SUFFIX", Pattern: "\\Bbar"},
+		&TokenType{Id: "BAR", Pattern: "\\bbar"},
+		&TokenType{Id: "SPC", Pattern: "\\s+"},
+	)
+
+	var tokens []*Token
+	for token := range l.LexTextSeq("foobar bar") {
+		tokens = append(tokens, token)
+	}
+
+	_, err := matchTokens(tokens, []*Token{
+		{l.Type("FOO"), "foo", 1, 1},
+		{l.Type("SUFFIX"), "bar", 1, 4},
+		{l.Type("SPC"), " ", 1, 7},
+		{l.Type("BAR"), "bar", 1, 8},
+		{TextEndType, "", 1, 11},
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrailingAssertions(t *testing.T) {
+	newLexer := func() *Lexer {
+		return NewLexer(
+			&TokenType{Id: "FOO", Pattern: "foo\\b"},
+			&TokenType{Id: "END", Pattern: "a$"},
+			&TokenType{Id: "A", Pattern: "a"},
+			&TokenType{Id: "X", Pattern: "x"},
+			&TokenType{Id: "SPC", Pattern: "\\s+"},
+		)
+	}
+	l := newLexer()
+
+	var tokens []*Token
+	for token := range l.LexTextSeq("foo x aa") {
+		tokens = append(tokens, token)
+	}
+	_, err := matchTokens(tokens, []*Token{
+		{l.Type("FOO"), "foo", 1, 1},
+		{l.Type("SPC"), " ", 1, 4},
+		{l.Type("X"), "x", 1, 5},
+		{l.Type("SPC"), " ", 1, 6},
+		{l.Type("A"), "a", 1, 7},
+		{l.Type("END"), "a", 1, 8},
+		{TextEndType, "", 1, 9},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	l = newLexer()
+	for token, e := range l.LexTextSeq("foox") {
+		if e == nil && token.Type == l.Type("FOO") {
+			t.Errorf("foo matched before x despite the word boundary")
+		}
+	}
+}
+
 func matchTokens(t1 []*Token, t2 []*Token) (bool, error) {
 	if len(t1) != len(t2) {
 		return false, errors.New(fmt.Sprint("comparing different number of tokens:", len(t1), ",", len(t2)))
@@ -306,3 +387,68 @@ func String(tokens []*Token) string {
 	}
 	return s.String()
 }
+
+func TestTokenTypeSyntaxError(t *testing.T) {
+	_, err := NewTokenType("ID", "[_a-zA-Z][_a-zA-Z0-9*")
+	var syntaxErr *regex.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Offset != 21 {
+		t.Error("expected a syntax error at offset 21, got", err)
+	}
+	if tokenType, err := NewTokenType("ID", "[_a-zA-Z][_a-zA-Z0-9]*"); err != nil || !tokenType.Compiled.Match("x1") {
+		t.Error("valid token type not created:", err)
+	}
+	if tokenType, err := NewTokenType("FLOAT", "[0-9]+\\.[0-9]*([eE][+-]?[0-9]+)?"); err != nil || !tokenType.Compiled.Match("1.5e-3") {
+		t.Error("token type with a trailing '-' in a set not created:", err)
+	}
+}
+
+// BenchmarkNewLexer compiles a lexer for the 83 tokens of a small programming language.
+func BenchmarkNewLexer(b *testing.B) {
+	var tokens []*TokenType
+	keywords := []string{"break", "case", "chan", "const", "continue", "default", "defer",
+		"else", "fallthrough", "for", "func", "go", "goto", "if", "import", "interface",
+		"map", "package", "range", "return", "select", "struct", "switch", "type", "var",
+		"true", "false", "nil", "int", "string", "bool", "float", "byte", "rune", "error"}
+	for _, k := range keywords {
+		tokens = append(tokens, &TokenType{Id: strings.ToUpper(k), Pattern: k})
+	}
+	operators := []string{"\\+", "-", "\\*", "/", "%", "&", "\\|", "\\^", "<<", ">>", "&\\^",
+		"\\+=", "-=", "\\*=", "/=", "%=", "&=", "\\|=", "\\^=", "<<=", ">>=", "&&", "\\|\\|",
+		"<-", "\\+\\+", "--", "==", "<", ">", "=", "!", "!=", "<=", ">=", ":=", "\\.\\.\\.",
+		"\\(", "\\)", "\\[", "\\]", "\\{", "\\}"}
+	for i, o := range operators {
+		tokens = append(tokens, &TokenType{Id: "OP" + strconv.Itoa(i), Pattern: o})
+	}
+	tokens = append(tokens,
+		&TokenType{Id: "ID", Pattern: "[_a-zA-Z][_a-zA-Z0-9]*"},
+		&TokenType{Id: "FLOAT", Pattern: "[0-9]+\\.[0-9]*([eE][+-]?[0-9]+)?"},
+		&TokenType{Id: "INT", Pattern: "0[xX][0-9a-fA-F]+|[0-9]+"},
+		&TokenType{Id: "STRING", Pattern: "\"([^\"\\\\\\n]|\\\\.)*\""},
+		&TokenType{Id: "COMMENT", Pattern: "//[^\\n]*|/\\*([^*]|\\*+[^*/])*\\*+/"},
+		&TokenType{Id: "SPC", Pattern: "\\s+"},
+	)
+	for range b.N {
+		for _, t := range tokens {
+			t.Compiled = nil
+		}
+		NewLexer(tokens...)
+	}
+}
+
+// BenchmarkNewLexerBounded compiles a lexer whose tokens have bounded lengths, as in
+// fixed-format records, with DFAs of hundreds to thousands of states.
+func BenchmarkNewLexerBounded(b *testing.B) {
+	tokens := []*TokenType{
+		{Id: "ID", Pattern: "[_a-zA-Z][_a-zA-Z0-9]{0,254}"},
+		{Id: "NUMBER", Pattern: "[0-9]{1,40}(\\.[0-9]{1,40})?([eE][+-]?[0-9]{1,3})?"},
+		{Id: "STRING", Pattern: "\"([^\"\\\\\\n]|\\\\.){0,500}\""},
+		{Id: "CODE", Pattern: "[A-Z]{3}-[0-9]{4}-[A-Z0-9]{1000}"},
+		{Id: "SPC", Pattern: "\\s+"},
+	}
+	for range b.N {
+		for _, t := range tokens {
+			t.Compiled = nil
+		}
+		NewLexer(tokens...)
+	}
+}
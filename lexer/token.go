@@ -52,12 +52,20 @@ var (
 	TextEndType = &TokenType{string(TextEnd), "$", regex.NewRegex(string(TextEnd))}
 )
 
+// SimpleTokenType creates a token type matching its id literally.
 func SimpleTokenType(id string) *TokenType {
-	return NewTokenType(id, regex.Escape(id))
+	pattern := regex.Escape(id)
+	return &TokenType{id, pattern, regex.MustCompile(pattern)}
 }
 
-func NewTokenType(id string, pattern string) *TokenType {
-	return &TokenType{id, pattern, regex.NewRegex(pattern)}
+// NewTokenType creates a token type matching the pattern, returning a *regex.SyntaxError
+// if the pattern is not a valid regular expression.
+func NewTokenType(id string, pattern string) (*TokenType, error) {
+	compiled, err := regex.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenType{id, pattern, compiled}, nil
 }
 
 func (t *TokenSeq) Next() (*Token, error, bool) {
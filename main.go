@@ -9,7 +9,7 @@ func main() {
 
 	r = regex.NewRegex("a")
 	println(r.Pattern.String())
-	println(r.Dfa.GraphViz(r.Pattern.String()))
+	println(r.Automaton().GraphViz(r.Pattern.String()))
 
 	r = regex.NewRegex("a|b")
 	println(r)
@@ -46,7 +46,7 @@ func main() {
 
 	r = regex.NewRegex("ab(cd|ef)?|a(fc)*\\*[a-z0-9ABC---]+")
 	println(r.String())
-	println(r.Dfa.GraphViz(r.String()))
+	println(r.Automaton().GraphViz(r.String()))
 
 	m = r.Matcher()
 	println(m.MatchNext('a'))
@@ -0,0 +1,329 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// The intersection, difference and complement of regular expressions are computed
+// on their DFAs with a product construction: a state of the resulting DFA is a tuple
+// of states of the DFAs combined, which all move together on each character, and is
+// final when the tuple is accepted by the operation (e.g., when all its states are
+// final for the intersection). Transitions are built over a disjoint partition of
+// the characters leaving the states of the tuple, including the characters leaving
+// none of them, which lead to the empty tuple. Thus, the resulting DFA is complete
+// and deterministic, as required by the complement, before it is trimmed and minimized.
+//
+// DFA states record the context of the previous character and are final depending
+// on the context of the next one (for assertions): the product keeps a start state
+// for each context and the operation is applied to the finality in each context.
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// combination is the pattern of a regular expression obtained by combining the
+	// languages of other regular expressions, which has no syntax of its own. It is
+	// written as the regular expression obtained from its DFA by state elimination,
+	// and its NFA is built from its DFA.
+	combination struct {
+		op       string
+		operands []Pattern
+		dfa      *automata
+
+		pattern Pattern // the regular expression of the DFA, once computed
+	}
+
+	// product is a state of a product automaton: the states reached in each of the
+	// DFAs combined. As the transitions leaving a DFA state can overlap, a set of
+	// states is kept for each DFA, which is empty when the DFA cannot match.
+	product [][]state
+
+	// edge is a transition from a state in one of the DFAs of a product.
+	edge struct {
+		dfa   int
+		spans spanSet
+		to    state
+	}
+)
+
+// Intersect returns the regular expression matching the strings matched by both
+// the regular expression and the other one.
+func (r *Regex) Intersect(other *Regex) *Regex {
+	return combine("&", []*Regex{r, other}, func(accepted []bool) bool {
+		return accepted[0] && accepted[1]
+	})
+}
+
+// Minus returns the regular expression matching the strings matched by the regular
+// expression but not by the other one, such as the identifiers which are not keywords.
+func (r *Regex) Minus(other *Regex) *Regex {
+	return combine("-", []*Regex{r, other}, func(accepted []bool) bool {
+		return accepted[0] && !accepted[1]
+	})
+}
+
+// Complement returns the regular expression matching all the strings which are not
+// matched by the regular expression.
+func (r *Regex) Complement() *Regex {
+	return combine("~", []*Regex{r}, func(accepted []bool) bool {
+		return !accepted[0]
+	})
+}
+
+// MatchNone returns true if the regular expression does not match any string, such
+// as the intersection of two regular expressions which do not overlap.
+func (r *Regex) MatchNone() bool {
+	return len(r.automaton().final) == 0
+}
+
+// combine returns the regular expression whose DFA is the product of the DFAs of the
+// regular expressions, accepting the strings for which accept returns true when given
+// whether each regular expression matches the string. Capturing groups are not kept
+// and word lists are combined as the words they match, without their weights.
+func combine(op string, regexes []*Regex, accept func(accepted []bool) bool) *Regex {
+	dfas := make([]*automata, len(regexes))
+	operands := make([]Pattern, len(regexes))
+	for i, r := range regexes {
+		dfas[i] = r.automaton()
+		operands[i] = r.Pattern
+	}
+	d := productDfa(dfas, accept).trim().minimize()
+	return &Regex{Pattern: &combination{op, operands, d, nil}, Dfa: d}
+}
+
+// productDfa returns the product of the DFAs, in which a state is final in the
+// contexts where accept returns true for the finality of the states of the tuple.
+func productDfa(dfas []*automata, accept func(accepted []bool) bool) *automata {
+	dfa := &automata{
+		Trans:     make(transitions),
+		starts:    map[context]state{},
+		final:     []state{},
+		finalMap:  map[state]bool{},
+		finalNext: map[state]set[context]{},
+	}
+
+	ids := map[state]int{}
+	states := map[string]state{}
+	products := map[state]product{}
+	var explored []state
+	add := func(p product) state {
+		key := p.key(ids)
+		s, ok := states[key]
+		if !ok {
+			s = &stateObj{}
+			states[key] = s
+			products[s] = p
+			explored = append(explored, s)
+			for _, next := range contexts {
+				accepted := make([]bool, len(dfas))
+				for i, d := range dfas {
+					for _, q := range p[i] {
+						accepted[i] = accepted[i] || d.finalNext[q][next]
+					}
+				}
+				if accept(accepted) {
+					if dfa.finalNext[s] == nil {
+						dfa.finalNext[s] = set[context]{}
+					}
+					dfa.finalNext[s][next] = true
+				}
+			}
+			if dfa.finalNext[s][atEdge] {
+				dfa.final = append(dfa.final, s)
+				dfa.finalMap[s] = true
+			}
+		}
+		return s
+	}
+
+	for _, c := range contexts {
+		start := make(product, len(dfas))
+		for i, d := range dfas {
+			start[i] = []state{d.startIn(c)}
+		}
+		dfa.starts[c] = add(start)
+	}
+	dfa.start = dfa.starts[atEdge]
+
+	for len(explored) > 0 {
+		source := explored[0]
+		explored = explored[1:]
+		p := products[source]
+
+		// the bounds of the spans of all transitions split the characters into
+		// intervals in which all characters lead to the same states
+		var edges []edge
+		bounds := []rune{0, utf8.MaxRune + 1}
+		for i, d := range dfas {
+			for _, q := range p[i] {
+				for c, t := range d.Trans[q] {
+					if spans := c.spanSet().compact(); spans != nil {
+						edges = append(edges, edge{i, spans, t})
+						for _, s := range spans {
+							bounds = append(bounds, s.from, s.to+1)
+						}
+					}
+				}
+			}
+		}
+		slices.Sort(bounds)
+		bounds = slices.Compact(bounds)
+
+		// intervals leading to the same tuple of states are combined into a single transition
+		targets := map[string]product{}
+		spans := map[string]spanSet{}
+		for i := 0; i < len(bounds)-1; i++ {
+			from, to := bounds[i], bounds[i+1]-1
+			target := make(product, len(dfas))
+			for _, e := range edges {
+				if e.spans.match(from) && !slices.Contains(target[e.dfa], e.to) {
+					target[e.dfa] = append(target[e.dfa], e.to)
+				}
+			}
+			key := target.key(ids)
+			targets[key] = target
+			spans[key] = append(spans[key], span{from, to})
+		}
+		dfa.Trans[source] = map[char]state{}
+		for key, target := range targets {
+			dfa.Trans[source][&charSet{mod: &modifier{}, span: spans[key].compact()}] = add(target)
+		}
+	}
+	return dfa
+}
+
+// disjoint returns a DFA accepting the same strings in which each character leads to
+// at most one state from each state, which is the product of the DFA alone.
+func (auto *automata) disjoint() *automata {
+	return productDfa([]*automata{auto}, func(accepted []bool) bool {
+		return accepted[0]
+	})
+}
+
+// key returns a string identifying the tuple of sets of states, using the ids to
+// number the states. The states of each set are sorted by their ids.
+func (p product) key(ids map[state]int) string {
+	var key strings.Builder
+	for i, states := range p {
+		if i > 0 {
+			key.WriteByte('|')
+		}
+		numbers := make([]int, len(states))
+		for j, s := range states {
+			id, ok := ids[s]
+			if !ok {
+				id = len(ids)
+				ids[s] = id
+			}
+			numbers[j] = id
+		}
+		slices.Sort(numbers)
+		for j, n := range numbers {
+			if j > 0 {
+				key.WriteByte(',')
+			}
+			key.WriteString(strconv.Itoa(n))
+		}
+	}
+	return key.String()
+}
+
+// startIn returns the start state of the DFA for a match following a character in
+// the context prev.
+func (auto *automata) startIn(prev context) state {
+	if s, ok := auto.starts[prev]; ok {
+		return s
+	}
+	return auto.start
+}
+
+func (c *combination) String() string {
+	return c.eliminated().String()
+}
+
+// eliminated returns the regular expression of the DFA of the combination.
+func (c *combination) eliminated() Pattern {
+	if c.pattern == nil {
+		c.pattern = c.dfa.Pattern()
+	}
+	return c.pattern
+}
+
+// nfa returns a copy of the DFA of the combination, with an empty transition from
+// its final states to the final state of the NFA. The contexts of the DFA are not
+// kept: the copy starts from the start state at the beginning of the input and its
+// final states are those at the end of the input.
+func (c *combination) nfa() *automata {
+	a := &automata{
+		Trans: make(transitions),
+		start: &stateObj{},
+		final: []state{&stateObj{}},
+	}
+	copies := map[state]state{}
+	copyOf := func(s state) state {
+		if _, ok := copies[s]; !ok {
+			copies[s] = &stateObj{}
+		}
+		return copies[s]
+	}
+	a.addTransitions(a.start, map[char]state{epsilon(): copyOf(c.dfa.start)})
+	for s, trans := range c.dfa.Trans {
+		for ch, t := range trans {
+			a.addTransitions(copyOf(s), map[char]state{ch: copyOf(t)})
+		}
+	}
+	for _, f := range c.dfa.final {
+		a.addTransitions(copyOf(f), map[char]state{epsilon(): a.final[0]})
+	}
+	return a
+}
+
+// Equivalent returns true if the regular expressions match the same strings. Otherwise,
+// it returns false with a shortest string matched by only one of them.
+func Equivalent(a, b *Regex) (bool, string) {
+	return differ(a, b, func(accepted []bool) bool {
+		return accepted[0] != accepted[1]
+	})
+}
+
+// Subset returns true if all the strings matched by the regular expression a are also
+// matched by b. Otherwise, it returns false with a shortest string matched by a but
+// not by b.
+func Subset(a, b *Regex) (bool, string) {
+	return differ(a, b, func(accepted []bool) bool {
+		return accepted[0] && !accepted[1]
+	})
+}
+
+// differ returns false with a shortest string accepted by the product of the DFAs of
+// the regular expressions with accept, or true if there is no such string.
+func differ(a, b *Regex, accept func(accepted []bool) bool) (bool, string) {
+	s, found := productDfa([]*automata{a.automaton(), b.automaton()}, accept).shortest()
+	return !found, s
+}
+
+// shortest returns a shortest string accepted by the DFA, preferring printable ASCII
+// characters, and false if the DFA does not accept any string. States are explored
+// breadth-first from the start state, and their transitions in order of their characters.
+func (auto *automata) shortest() (string, bool) {
+	paths := map[state][]rune{auto.start: {}}
+	pending := []state{auto.start}
+	for len(pending) > 0 {
+		s := pending[0]
+		pending = pending[1:]
+		if auto.finalMap[s] {
+			return string(paths[s]), true
+		}
+		for _, t := range auto.sortedTransitions(s) {
+			if spans := t.char.spanSet(); len(spans) > 0 {
+				if _, ok := paths[t.to]; !ok {
+					paths[t.to] = append(slices.Clone(paths[s]), spans.representative())
+					pending = append(pending, t.to)
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,144 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestAlgebra(t *testing.T) {
+	identifier := NewRegex("[a-z][a-z0-9]*")
+	keyword := NewRegex("if|else|for")
+	digits := NewRegex("[0-9]+")
+	tests := []struct {
+		name     string
+		regex    *Regex
+		matched  []string
+		rejected []string
+	}{
+		{"identifier-keyword", identifier.Minus(keyword), []string{"x", "iff", "fo", "for1", "els"}, []string{"", "if", "else", "for", "1x"}},
+		{"identifier&keyword", identifier.Intersect(keyword), []string{"if", "else", "for"}, []string{"", "x", "iff"}},
+		{"~digits", digits.Complement(), []string{"", "a", "1a", "a1", "\n"}, []string{"1", "42"}},
+		{"identifier&.*o.*", identifier.Intersect(NewRegex(".*o.*")), []string{"o", "foo", "fo1"}, []string{"bar", "1o"}},
+		{"~~digits", digits.Complement().Complement(), []string{"1", "42"}, []string{"", "a"}},
+		{"digits-digits", digits.Minus(digits), nil, []string{"", "1", "a"}},
+	}
+	for _, test := range tests {
+		for _, s := range test.matched {
+			if !test.regex.Match(s) {
+				t.Errorf("%s did not match %q", test.name, s)
+			}
+		}
+		for _, s := range test.rejected {
+			if test.regex.Match(s) {
+				t.Errorf("%s matched %q", test.name, s)
+			}
+		}
+	}
+}
+
+func TestAlgebraAssertions(t *testing.T) {
+	r := NewRegex("\\w+\\b").Intersect(NewRegex("a.*"))
+	if !r.Match("ab") {
+		t.Error("'\\w+\\b' & 'a.*' did not match 'ab'")
+	}
+	m := r.Matcher()
+	m.MatchNext('a')
+	if m.FullMatchBefore('b') {
+		t.Error("'\\w+\\b' & 'a.*' matched 'a' before 'b'")
+	}
+	if !m.FullMatchBefore(' ') {
+		t.Error("'\\w+\\b' & 'a.*' did not match 'a' before ' '")
+	}
+	f := NewRegex("\\b[a-z]+\\b").Minus(NewRegex("if")).Find("if xy")
+	if f == nil || f.Text != "xy" {
+		t.Errorf("'\\b[a-z]+\\b' - 'if' did not find 'xy' in 'if xy': %v", f)
+	}
+}
+
+func TestAlgebraGenerate(t *testing.T) {
+	identifier := NewRegex("[a-c]{1,3}")
+	keyword := NewRegex("a|ab|abc")
+	r := identifier.Minus(keyword)
+	for i := 0; i < 100; i++ {
+		s := r.Generate()
+		if !identifier.Match(s) || keyword.Match(s) {
+			t.Errorf("%s generated %q", r, s)
+		}
+	}
+}
+
+func TestMatchNone(t *testing.T) {
+	if !NewRegex("[a-z]+").Intersect(NewRegex("[0-9]+")).MatchNone() {
+		t.Error("'[a-z]+' and '[0-9]+' overlap")
+	}
+	if NewRegex("[a-z0-9]+").Intersect(NewRegex("[0-9]+")).MatchNone() {
+		t.Error("'[a-z0-9]+' and '[0-9]+' do not overlap")
+	}
+	if NewRegex(".*").Complement().Match("") {
+		t.Error("complement of '.*' matched ''")
+	}
+}
+
+func TestCombinationPattern(t *testing.T) {
+	r := NewRegex("[a-z]+").Minus(NewRegex("if"))
+	if r.String() != "i|(?:[a-hj-z]|i(?:[a-eg-z]|f[a-z]))[a-z]*" {
+		t.Errorf("unexpected pattern %q", r.String())
+	}
+	s := &sequence{[]Pattern{r.Pattern, &singleChar{&modifier{}, '!'}}}
+	d := s.nfa().dfa().minimize()
+	re := &Regex{Pattern: s, Dfa: d}
+	if !re.Match("x!") || re.Match("if!") {
+		t.Errorf("NFA of %s is wrong", s)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		equivalent     bool
+		counterexample string
+	}{
+		{"[a-z]+", "[a-z][a-z]*", true, ""},
+		{"(a|b)*", "(a*b*)*", true, ""},
+		{"a{2,3}", "aa|aaa", true, ""},
+		{"[0-9]+", "\\d+", true, ""},
+		{"(?i)abc", "[aA][bB][cC]", true, ""},
+		{"\\bab", "ab", true, ""},
+		{"a+", "a*", false, ""},
+		{"a{2,4}", "a{2,3}", false, "aaaa"},
+		{"[a-z]+", "[a-y]+", false, "z"},
+		{"x[0-9]*y", "x[0-9]+y", false, "xy"},
+		{"(ab)+", "ab|abab", false, "ababab"},
+	}
+	for _, test := range tests {
+		equivalent, counterexample := Equivalent(NewRegex(test.a), NewRegex(test.b))
+		if equivalent != test.equivalent || counterexample != test.counterexample {
+			t.Errorf("Equivalent(%q, %q): expected %v %q, got %v %q", test.a, test.b,
+				test.equivalent, test.counterexample, equivalent, counterexample)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		subset         bool
+		counterexample string
+	}{
+		{"if|else", "[a-z]+", true, ""},
+		{"a{2,3}", "a+", true, ""},
+		{"", "a*", true, ""},
+		{"[a-z]+", "if|else", false, "a"},
+		{"a*", "a+", false, ""},
+		{"[0-9]{1,3}", "[0-9]{2}", false, "0"},
+		{"\\n|x", "x", false, "\n"},
+	}
+	for _, test := range tests {
+		subset, counterexample := Subset(NewRegex(test.a), NewRegex(test.b))
+		if subset != test.subset || counterexample != test.counterexample {
+			t.Errorf("Subset(%q, %q): expected %v %q, got %v %q", test.a, test.b,
+				test.subset, test.counterexample, subset, counterexample)
+		}
+	}
+}
@@ -5,7 +5,6 @@ package regex
 import (
 	"container/list"
 	"maps"
-	"reflect"
 	"slices"
 	"strconv"
 	"strings"
@@ -22,101 +21,243 @@ type (
 	set[T comparable] map[T]bool
 
 	automata struct {
-		Trans    transitions
-		start    state
-		final    []state
-		finalMap map[state]bool
+		Trans     transitions
+		start     state
+		starts    map[context]state // start states by context of the previous character (DFA only)
+		final     []state
+		finalMap  map[state]bool
+		finalNext map[state]set[context] // contexts of the next character in which a DFA state is final
+	}
+
+	// dfaState is the set of NFA states corresponding to a DFA state, and the
+	// context of the character consumed to reach it.
+	dfaState struct {
+		states set[state]
+		prev   context
 	}
 )
 
 // Thomson's algorithm for converting regular expression to DFA.
+//
+// When the NFA contains assertions (^, $, \b, \B), a DFA state is the set of NFA
+// states reached together with the context of the previous character. The assertions
+// are followed when computing the closure of the set for the context of the next
+// character, which requires splitting the outgoing transitions by that context. There
+// is also a start state for every context of the character preceding the match.
 func (auto *automata) dfa() *automata {
 	dfa := automata{
-		Trans:    make(transitions),
-		start:    nil,
-		final:    []state{},
-		finalMap: map[state]bool{},
+		Trans:     make(transitions),
+		start:     nil,
+		starts:    map[context]state{},
+		final:     []state{},
+		finalMap:  map[state]bool{},
+		finalNext: map[state]set[context]{},
 	}
 
-	dfaStates := map[state]set[state]{}
-	explored := make(chan set[state], 1000)
-	reachable := &set[state]{}
-	eClosure(auto.start, auto.Trans, reachable)
-	explored <- *reachable
-
-	dfa.start = &stateObj{}
-	dfaStates[dfa.start] = *reachable
-	if auto.containsFinal(reachable) {
-		dfa.final = append(dfa.final, dfa.start)
-		dfa.finalMap[dfa.start] = true
+	// without assertions, the contexts are irrelevant and a single one is used
+	anchored := auto.anchored()
+	nextContexts := []context{atEdge}
+	if anchored {
+		nextContexts = []context{wordChar, newline, otherChar}
 	}
 
-	for len(explored) > 0 {
-		dfaState := <-explored
-		source := find(dfaStates, dfaState)
-
-		// union all outgoing character transitions on any State of the DFA State
-		chars := map[string][]char{}
-		for s := range dfaState {
-			trans := auto.Trans[s]
-			for c := range trans {
-				if !c.isEmpty() {
-					pattern := c.String()
-					chars[pattern] = append(chars[pattern], c)
+	dfaStates := map[state]dfaState{}
+	ids := map[state]int{}
+	keys := map[string]state{}
+	var explored []state
+	add := func(reachable set[state], prev context) state {
+		key := dfaState{reachable, prev}.key(ids)
+		s, ok := keys[key]
+		if !ok {
+			s = &stateObj{}
+			keys[key] = s
+			dfaStates[s] = dfaState{reachable, prev}
+			explored = append(explored, s)
+			for _, next := range contexts {
+				if auto.containsFinal(auto.closure(reachable, prev, next, anchored)) {
+					if dfa.finalNext[s] == nil {
+						dfa.finalNext[s] = set[context]{}
+					}
+					dfa.finalNext[s][next] = true
 				}
 			}
+			if dfa.finalNext[s][atEdge] {
+				dfa.final = append(dfa.final, s)
+				dfa.finalMap[s] = true
+			}
 		}
+		return s
+	}
 
-		// find reachable set of states for each outgoing character
-		for _, cs := range chars {
-			reachable = &set[state]{}
-			groups := set[int]{}
-			var combinedChar char = nil
-			for _, c := range cs {
-				if combinedChar == nil {
-					combinedChar = c
-				}
-				for i := c.groups().Front(); i != nil; i = i.Next() {
-					groups[i.Value.(int)] = true
+	addTransition := func(from state, c char, to state) {
+		if dfa.Trans[from] == nil {
+			dfa.Trans[from] = map[char]state{}
+		}
+		dfa.Trans[from][c] = to
+	}
+
+	reachable := &set[state]{}
+	eClosure(auto.start, auto.Trans, reachable, nil)
+	dfa.start = add(*reachable, atEdge)
+	for _, c := range contexts {
+		if anchored {
+			dfa.starts[c] = add(*reachable, c)
+		} else {
+			dfa.starts[c] = dfa.start
+		}
+	}
+
+	for len(explored) > 0 {
+		source := explored[0]
+		explored = explored[1:]
+		sourceState := dfaStates[source]
+
+		for _, next := range nextContexts {
+			current := auto.closure(sourceState.states, sourceState.prev, next, anchored)
+
+			// the bounds of the spans of the outgoing characters of all the NFA states split
+			// the characters into disjoint intervals, in which all characters lead to the same
+			// NFA states, so that a character matches a single transition of the DFA state
+			var moves []move
+			var bounds []rune
+			lists := map[string][]move{}
+			for s := range current {
+				for c, t := range auto.Trans[s] {
+					if c.isEmpty() {
+						continue
+					}
+					spans := slices.Clone(c.spanSet()).compact()
+					if spans == nil {
+						// characters without spans (word lists) are only used for generation and
+						// are not split: they are added once, in the last context
+						if !anchored || next == otherChar {
+							lists[c.String()] = append(lists[c.String()], move{c, nil, t})
+						}
+						continue
+					}
+					if anchored {
+						// restrict the character to those in the context of the next character
+						spans = spans.intersection(next.spans())
+					}
+					if len(spans) == 0 {
+						continue
+					}
+					moves = append(moves, move{c, spans, t})
+					for _, sp := range spans {
+						bounds = append(bounds, sp.from, sp.to+1)
+					}
 				}
-				for s := range dfaState {
-					trans := auto.Trans[s]
-					if t, ok := trans[c]; ok {
-						eClosure(t, auto.Trans, reachable)
+			}
+			slices.Sort(bounds)
+			bounds = slices.Compact(bounds)
+
+			// intervals leading to the same NFA states are combined into a single transition,
+			// on a set of characters which matches exactly the characters of the intervals
+			targets := map[string]set[state]{}
+			spans := map[string]spanSet{}
+			for i := 0; i < len(bounds)-1; i++ {
+				from, to := bounds[i], bounds[i+1]-1
+				reachable := set[state]{}
+				for _, m := range moves {
+					if m.spans.match(from) {
+						eClosure(m.to, auto.Trans, &reachable, nil)
 					}
 				}
+				if len(reachable) == 0 {
+					continue
+				}
+				key := dfaState{reachable, next}.key(ids)
+				targets[key] = reachable
+				spans[key] = append(spans[key], span{from, to})
 			}
-
-			union := slices.Sorted(maps.Keys(groups))
-			newGroups := list.New()
-			for _, g := range union {
-				newGroups.PushBack(g)
+			for key, reachable := range targets {
+				addTransition(source, &charSet{mod: &modifier{}, span: spans[key].compact()}, add(reachable, next))
 			}
-			combinedChar.setGroups(newGroups)
-
-			target := find(dfaStates, *reachable)
-			if target == nil {
-				target = &stateObj{}
-				dfaStates[target] = *reachable
-				explored <- *reachable
+			for _, ms := range lists {
+				reachable := set[state]{}
+				for _, m := range ms {
+					eClosure(m.to, auto.Trans, &reachable, nil)
+				}
+				addTransition(source, ms[0].char, add(reachable, next))
 			}
-			if auto.containsFinal(reachable) && !dfa.finalMap[target] {
-				dfa.final = append(dfa.final, target)
-				dfa.finalMap[target] = true
+		}
+	}
+	return dfa.trim()
+}
+
+// move is a transition of an NFA state, with its characters in the context of the next
+// character when the NFA has assertions.
+type move struct {
+	char  char
+	spans spanSet
+	to    state
+}
+
+// closure returns the set of NFA states reachable from the states through empty
+// transitions and the assertions that hold between the prev and next contexts.
+func (auto *automata) closure(states set[state], prev, next context, anchored bool) set[state] {
+	if !anchored {
+		// without assertions, the states are already closed under empty transitions
+		return states
+	}
+	closure := set[state]{}
+	holds := func(a *assertion) bool {
+		return a.holds(prev, next)
+	}
+	for s := range states {
+		eClosure(s, auto.Trans, &closure, holds)
+	}
+	return closure
+}
+
+// anchored returns true if the automata contains assertions on positions.
+func (auto *automata) anchored() bool {
+	for _, trans := range auto.Trans {
+		for c := range trans {
+			if _, ok := c.(*assertion); ok {
+				return true
 			}
-			_, ok := dfa.Trans[source]
-			if !ok {
-				dfa.Trans[source] = map[char]state{}
+		}
+	}
+	return false
+}
+
+// trim removes the states from which no final state can be reached, except for the
+// start states, so that every transition of the automata can lead to a full match.
+func (auto *automata) trim() *automata {
+	reverse := map[state][]state{}
+	for s, trans := range auto.Trans {
+		for _, t := range trans {
+			reverse[t] = append(reverse[t], s)
+		}
+	}
+	live := set[state]{}
+	pending := slices.Collect(maps.Keys(auto.finalNext))
+	for len(pending) > 0 {
+		s := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !live[s] {
+			live[s] = true
+			pending = append(pending, reverse[s]...)
+		}
+	}
+	for s, trans := range auto.Trans {
+		for c, t := range trans {
+			if !live[t] {
+				delete(trans, c)
 			}
-			dfa.Trans[source][combinedChar] = target
+		}
+		if !live[s] || len(trans) == 0 {
+			delete(auto.Trans, s)
 		}
 	}
-	return &dfa
+	return auto
 }
 
-// Hopcroft's algorithm for minimizing DFA
+// minimize merges the equivalent states of the DFA, refining a partition of its states
+// until the states in each part cannot be distinguished (see refine).
 func (auto *automata) minimize() *automata {
-	// initially partition states into two: one containing final states and the other, non-final states
+	// initially partition states by the contexts in which they are final
 	partitions := map[state]int{}
 	partitionSize := map[int]int{}
 	for s, trans := range auto.Trans {
@@ -126,51 +267,22 @@ func (auto *automata) minimize() *automata {
 		}
 	}
 	auto.partition(auto.start, partitions, partitionSize)
+	for _, s := range auto.starts {
+		auto.partition(s, partitions, partitionSize)
+	}
 	for _, f := range auto.final {
 		auto.partition(f, partitions, partitionSize)
 	}
-	maxPartitions := len(partitions) - 1 // special-case for empty regex having single partition containing final state
-
-	// split partitions by extracting subset of states equivalent to each other.
-	// 2 states are equivalent if they have the same outgoing character transitions
-	// to states that are in the same partition. e.g.: (s1, s2) --c--> (t1, t2)
-	// continue until no more equivalence classes can be extracted.
-	for changed := true; changed; {
-		changed = false
-
-	split:
-		for s, sPartition := range partitions {
-			if partitionSize[sPartition] > 1 {
-				for c1, t1 := range auto.Trans[s] {
-					equivPartition := []state{s}
-					for other, otherPartition := range partitions {
-						if other != s && otherPartition == sPartition {
-							equivalent := len(auto.Trans[other]) != 0
-							for c2, t2 := range auto.Trans[other] {
-								if partitions[t1] != partitions[t2] || !slices.Equal(c1.spanSet(), c2.spanSet()) {
-									equivalent = false
-									break
-								}
-							}
-							if equivalent {
-								equivPartition = append(equivPartition, other)
-								break
-							}
-						}
-					}
-					if len(equivPartition) < partitionSize[sPartition] {
-						maxPartitions++
-						for _, t := range equivPartition {
-							partitions[t] = maxPartitions
-						}
-						partitionSize[sPartition] -= len(equivPartition)
-						partitionSize[maxPartitions] = len(equivPartition)
-						changed = true
-						break split
-					}
-				}
-			}
-		}
+
+	// refine the partitions until the states in each cannot be distinguished
+	var states []state
+	index := map[state]int{}
+	for s := range partitions {
+		index[s] = len(states)
+		states = append(states, s)
+	}
+	for i, p := range auto.refine(states, index, partitions) {
+		partitions[states[i]] = p
 	}
 
 	// construct minimized DFA with each partition as a separate state
@@ -184,7 +296,15 @@ func (auto *automata) minimize() *automata {
 	}
 
 	newTrans := map[state]map[state][]char{}
-	newAuto := &automata{Trans: make(transitions), finalMap: make(map[state]bool)}
+	newAuto := &automata{
+		Trans:     make(transitions),
+		starts:    map[context]state{},
+		finalMap:  make(map[state]bool),
+		finalNext: map[state]set[context]{},
+	}
+	for c, s := range auto.starts {
+		newAuto.starts[c] = newStates[partitions[s]]
+	}
 	for p, states := range splits {
 		from := newStates[p]
 		for _, s := range states {
@@ -195,6 +315,9 @@ func (auto *automata) minimize() *automata {
 				newAuto.final = append(newAuto.final, from)
 				newAuto.finalMap[from] = true
 			}
+			if auto.finalNext[s] != nil {
+				newAuto.finalNext[from] = auto.finalNext[s]
+			}
 
 			// combine character transitions for identical pair of states
 			for c, t := range auto.Trans[s] {
@@ -224,14 +347,10 @@ func (auto *automata) minimize() *automata {
 				newAuto.Trans[from][trans[0]] = to
 			} else {
 				charSets := list.New()
-				groups := list.New()
 				for _, c := range trans {
 					charSets.PushBack(c)
-					for i := c.groups().Front(); i != nil; i = i.Next() {
-						groups.PushBack(i.Value.(int))
-					}
 				}
-				ch := &charSet{trans[0].modifier(), false, *charSets, *groups, nil}
+				ch := &charSet{trans[0].modifier(), false, *charSets, nil}
 				newAuto.Trans[from][ch] = to
 			}
 		}
@@ -239,11 +358,173 @@ func (auto *automata) minimize() *automata {
 	return newAuto
 }
 
-// Add state to partitions and increase partition size, if necessary.
+// refine returns the coarsest partition of the states, numbered by index, which refines
+// their initial partitions and in which the states of a part have transitions on the
+// same characters to the same parts, using Hopcroft's algorithm. The characters of the
+// transitions are split into disjoint intervals, the letters of the DFA, so that each
+// letter leads to at most one state from a state. Each part is then split by the states
+// with a transition on a letter into a part taken from a worklist, which initially
+// holds all parts, as transitions can be missing. Only the smaller half of a part split
+// is added to the worklist, as splitting by a part and one half splits by the other.
+//
+// A state with transitions on overlapping characters to different states is kept in a
+// part of its own, as such states cannot be compared letter by letter.
+func (auto *automata) refine(states []state, index map[state]int, initial map[state]int) []int {
+	// the bounds of the spans of all transitions split the characters into the letters,
+	// while characters without spans (word lists generating whole words) are letters
+	// of their own
+	var bounds []rune
+	others := map[string]int{}
+	for _, trans := range auto.Trans {
+		for c := range trans {
+			if spans := c.spanSet(); spans != nil {
+				for _, s := range spans {
+					bounds = append(bounds, s.from, s.to+1)
+				}
+			} else if _, ok := others[c.String()]; !ok {
+				others[c.String()] = len(others)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	letters := len(bounds) + len(others)
+
+	// into[t] are the transitions into the state t, by letter
+	type incoming struct{ letter, from int }
+	n := len(states)
+	into := make([][]incoming, n)
+	single := make([]bool, n)
+	target := make([]int, letters)
+	seen := make([]int, letters) // index+1 of the last state with a transition on a letter
+	var used []int
+	for i, s := range states {
+		used = used[:0]
+		add := func(l, to int) {
+			if seen[l] != i+1 {
+				seen[l], target[l] = i+1, to
+				used = append(used, l)
+			} else if target[l] != to {
+				single[i] = true
+			}
+		}
+		for c, t := range auto.Trans[s] {
+			to := index[t]
+			spans := c.spanSet()
+			if spans == nil {
+				add(len(bounds)+others[c.String()], to)
+			}
+			for _, sp := range spans {
+				l, _ := slices.BinarySearch(bounds, sp.from)
+				for ; l < len(bounds) && bounds[l] <= sp.to; l++ {
+					add(l, to)
+				}
+			}
+		}
+		if !single[i] {
+			for _, l := range used {
+				into[target[l]] = append(into[target[l]], incoming{l, i})
+			}
+		}
+	}
+
+	// the states are ordered by part in elems, each part being a range of elems, with
+	// the states found in a pre-image moved to the start of the range of their part
+	elems := make([]int, 0, n)
+	loc := make([]int, n)
+	part := make([]int, n)
+	var first, end, marked []int
+	addPart := func(members []int) {
+		p := len(first)
+		first = append(first, len(elems))
+		for _, s := range members {
+			part[s], loc[s] = p, len(elems)
+			elems = append(elems, s)
+		}
+		end = append(end, len(elems))
+		marked = append(marked, 0)
+	}
+	groups := map[int][]int{}
+	for i, s := range states {
+		if single[i] {
+			addPart([]int{i})
+		} else {
+			groups[initial[s]] = append(groups[initial[s]], i)
+		}
+	}
+	for _, members := range groups {
+		addPart(members)
+	}
+
+	var pending []int
+	for p := range first {
+		pending = append(pending, p)
+	}
+	sources := make([][]int, letters)
+	var touchedLetters, touchedParts []int
+	for len(pending) > 0 {
+		splitter := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		// the pre-image of the splitter on each letter, found before splitting any part
+		touchedLetters = touchedLetters[:0]
+		for _, t := range elems[first[splitter]:end[splitter]] {
+			for _, e := range into[t] {
+				if len(sources[e.letter]) == 0 {
+					touchedLetters = append(touchedLetters, e.letter)
+				}
+				sources[e.letter] = append(sources[e.letter], e.from)
+			}
+		}
+
+		for _, l := range touchedLetters {
+			touchedParts = touchedParts[:0]
+			for _, s := range sources[l] {
+				p := part[s]
+				if marked[p] == 0 {
+					touchedParts = append(touchedParts, p)
+				}
+				j := first[p] + marked[p]
+				other := elems[j]
+				elems[j], elems[loc[s]] = s, other
+				loc[other], loc[s] = loc[s], j
+				marked[p]++
+			}
+			sources[l] = sources[l][:0]
+
+			// split the parts with some states in the pre-image, the smaller half becoming
+			// a new part
+			for _, p := range touchedParts {
+				m := marked[p]
+				marked[p] = 0
+				if m == end[p]-first[p] {
+					continue
+				}
+				split := len(first)
+				if m <= end[p]-first[p]-m {
+					first, end = append(first, first[p]), append(end, first[p]+m)
+					first[p] += m
+				} else {
+					first, end = append(first, first[p]+m), append(end, end[p])
+					end[p] = first[p] + m
+				}
+				marked = append(marked, 0)
+				for _, s := range elems[first[split]:end[split]] {
+					part[s] = split
+				}
+				pending = append(pending, split)
+			}
+		}
+	}
+	return part
+}
+
+// Add state to partitions and increase partition size, if necessary. States are
+// initially partitioned by the contexts of the next character in which they are final.
 func (auto *automata) partition(s state, partitions map[state]int, partitionSize map[int]int) {
-	p := 1
-	if auto.finalMap[s] {
-		p = 0
+	p := 0
+	for c := range auto.finalNext[s] {
+		p |= 1 << c
 	}
 	if _, ok := partitions[s]; !ok {
 		partitions[s] = p
@@ -262,13 +543,8 @@ func (auto *automata) chars() map[char]bool {
 	return chars
 }
 
-func (auto *automata) containsFinal(reachable *set[state]) bool {
-	for s := range *reachable {
-		if s == auto.final[0] {
-			return true
-		}
-	}
-	return false
+func (auto *automata) containsFinal(reachable set[state]) bool {
+	return reachable[auto.final[0]]
 }
 
 func (auto *automata) merge(source *automata) *automata {
@@ -328,45 +604,47 @@ func (auto *automata) GraphViz(title string) string {
 				nodeNames[t] = strconv.Itoa(nodeCount)
 				nodeCount++
 			}
-			spec += "\t\"" + nodeNames[s] + "\" -> \"" + nodeNames[t] + "\" [label=\"" + c.String() + ":" + label(c.groups()) + "\"]\n"
+			spec += "\t\"" + nodeNames[s] + "\" -> \"" + nodeNames[t] + "\" [label=\"" + c.String() + "\"]\n"
 		}
 	}
 	spec += "}"
 	return spec
 }
 
-func eClosure(from state, trans transitions, closure *set[state]) {
+// eClosure adds to closure all states reachable from the state through empty transitions.
+// Assertions are followed only if holds is not nil and returns true for them.
+func eClosure(from state, trans transitions, closure *set[state], holds func(*assertion) bool) {
 	(*closure)[from] = true
 	for ch, to := range trans[from] {
 		if ch.isEmpty() && !(*closure)[to] {
-			eClosure(to, trans, closure)
+			if a, ok := ch.(*assertion); ok && (holds == nil || !holds(a)) {
+				continue
+			}
+			eClosure(to, trans, closure, holds)
 		}
 	}
 }
 
-func find(states map[state]set[state], state set[state]) state {
-	for k, v := range states {
-		if reflect.DeepEqual(v, state) {
-			return k
+// key returns a string identifying the DFA state, made of the context and the sorted
+// numbers of its NFA states, using the ids to number the states.
+func (d dfaState) key(ids map[state]int) string {
+	numbers := make([]int, 0, len(d.states))
+	for s := range d.states {
+		id, ok := ids[s]
+		if !ok {
+			id = len(ids)
+			ids[s] = id
 		}
+		numbers = append(numbers, id)
 	}
-	return nil
-}
-
-func label(groups *list.List) string {
-	var s strings.Builder
-	if groups != nil {
-		first := true
-		for g := groups.Front(); g != nil; g = g.Next() {
-			if first {
-				first = false
-			} else {
-				s.WriteRune(',')
-			}
-			s.WriteString(strconv.Itoa(g.Value.(int)))
-		}
+	slices.Sort(numbers)
+	var key strings.Builder
+	key.WriteString(strconv.Itoa(int(d.prev)))
+	for _, n := range numbers {
+		key.WriteByte(',')
+		key.WriteString(strconv.Itoa(n))
 	}
-	return s.String()
+	return key.String()
 }
 
 func charNfa(c char) *automata {
@@ -2,6 +2,7 @@ package regex
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 )
 
@@ -28,3 +29,103 @@ func TestDfaMinimization3(t *testing.T) {
 	minDfa := r.Dfa.minimize()
 	fmt.Println(minDfa.GraphViz("min (a(b|c)*){10,15}"))
 }
+
+func TestMinimizedStates(t *testing.T) {
+	tests := []struct {
+		pattern string
+		states  int
+	}{
+		{"fee|fie", 4},
+		{"a(b|c)*", 2},
+		{"[a-z]{5}", 6},
+		{"(a|b)*a(a|b){3}", 16},
+		{"(if|in)[a-z]*", 3},
+		{"[0-9]+|[0-9]+\\.[0-9]+", 4},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			dfa := NewRegex(test.pattern).Dfa
+			states := set[state]{dfa.start: true}
+			for s, trans := range dfa.Trans {
+				states[s] = true
+				for _, to := range trans {
+					states[to] = true
+				}
+			}
+			if len(states) != test.states {
+				t.Errorf("minimized DFA has %d states, expected %d", len(states), test.states)
+			}
+		})
+	}
+}
+
+func TestDisjointTransitions(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"el|[a-z]+", []string{"e", "el", "else", "l"}, []string{"", "E", "e1"}},
+		{".{0,3}x", []string{"x", "abx", "xxxx"}, []string{"", "abcdx", "xa"}},
+		{"(?i)k|[a-j]", []string{"k", "K", "a"}, []string{"l", "kk"}},
+		{"\\bif\\b|[a-z]+", []string{"if", "iff", "i"}, []string{"if!"}},
+		{"[0-9]+|[0-9]+\\.[0-9]+|0x[0-9a-f]+", []string{"0", "12.5", "0x1f"}, []string{"0x", "1."}},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			r := NewRegex(test.pattern)
+			for s, trans := range r.Dfa.Trans {
+				var spans spanSet
+				for c := range trans {
+					spans = append(spans, c.spanSet()...)
+				}
+				if spans.len() != slices.Clone(spans).compact().len() {
+					t.Errorf("transitions of %p on overlapping characters: %s", s, spans)
+				}
+			}
+			for range 10 {
+				for _, s := range test.match {
+					if !r.Match(s) {
+						t.Errorf("%q not matched", s)
+					}
+				}
+				for _, s := range test.noMatch {
+					if r.Match(s) {
+						t.Errorf("%q matched", s)
+					}
+				}
+			}
+		})
+	}
+}
+
+// benchmarkPatterns have DFAs with many states before minimization.
+var benchmarkPatterns = []string{
+	"(a|b)*a(a|b){10}",
+	"(a(b|c)*){10,15}",
+	"[a-z]{3000}",
+	"(if|else|for|while|return|[a-z_][a-z0-9_]*|[0-9]+(\\.[0-9]+)?){1,4}",
+	"(:word_en:ut)",
+}
+
+func BenchmarkSubsetConstruction(b *testing.B) {
+	for _, pattern := range benchmarkPatterns {
+		nfa := NewRegex(pattern).Pattern.nfa()
+		b.Run(pattern, func(b *testing.B) {
+			for range b.N {
+				nfa.dfa()
+			}
+		})
+	}
+}
+
+func BenchmarkMinimize(b *testing.B) {
+	for _, pattern := range benchmarkPatterns {
+		dfa := NewRegex(pattern).Pattern.nfa().dfa()
+		b.Run(pattern, func(b *testing.B) {
+			for range b.N {
+				dfa.minimize()
+			}
+		})
+	}
+}
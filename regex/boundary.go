@@ -0,0 +1,142 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Boundary matchers (^, $, \b, \B) are zero-width assertions on the characters
+// on each side of a position in the input. In the NFA they are epsilon transitions
+// which can only be followed when the assertion holds. The DFA construction
+// resolves them by recording, in each DFA state, the context of the previous
+// character and by splitting outgoing transitions by the context of the next one.
+
+import "math/rand"
+
+type (
+	// Boundary is the kind of position matched by an assertion.
+	Boundary uint8
+
+	// assertion matches a position in the input instead of a character.
+	assertion struct {
+		mod  *modifier
+		kind Boundary
+	}
+
+	// context classifies the character on one side of a position in the input.
+	context uint8
+)
+
+const (
+	TextStart       Boundary = iota // ^, at the start of the input, or of a line in multiline mode
+	TextEnd                         // $, at the end of the input, or of a line in multiline mode
+	WordBoundary                    // \b, between a word character and another character
+	NonWordBoundary                 // \B, elsewhere than at a word boundary
+)
+
+const (
+	// atEdge is the context before the first character of the input (when it
+	// is the previous context) or after its last character (as the next context).
+	atEdge context = iota
+
+	// wordChar is the context of a word character ([0-9a-zA-Z_]).
+	wordChar
+
+	// otherChar is the context of any other character.
+	otherChar
+
+	// newline is the context of the newline character, which is not a word
+	// character, and where ^ and $ match in multiline mode.
+	newline
+)
+
+var (
+	// contexts are the possible contexts of the previous character, each one
+	// having its own start state in a DFA.
+	contexts = []context{atEdge, wordChar, otherChar, newline}
+
+	wordSpans    = spanSet{{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}}
+	newlineSpans = spanSet{{'\n', '\n'}}
+	otherSpans   = wordSpans.invertUnicode().minus(newlineSpans)
+)
+
+// contextOf returns the context of the character r.
+func contextOf(r rune) context {
+	if wordSpans.match(r) {
+		return wordChar
+	} else if r == '\n' {
+		return newline
+	}
+	return otherChar
+}
+
+// spans returns the characters of a context of the next character.
+func (c context) spans() spanSet {
+	switch c {
+	case wordChar:
+		return wordSpans
+	case otherChar:
+		return otherSpans
+	case newline:
+		return newlineSpans
+	default:
+		return nil
+	}
+}
+
+// holds returns true if the assertion is satisfied at a position between a
+// character in the prev context and one in the next context.
+func (c *assertion) holds(prev, next context) bool {
+	switch c.kind {
+	case TextStart:
+		return prev == atEdge || (prev == newline && c.mod.multiline)
+	case TextEnd:
+		return next == atEdge || (next == newline && c.mod.multiline)
+	case WordBoundary:
+		return (prev == wordChar) != (next == wordChar)
+	case NonWordBoundary:
+		return (prev == wordChar) == (next == wordChar)
+	}
+	return false
+}
+
+func (c *assertion) String() string {
+	switch c.kind {
+	case TextStart:
+		if c.mod.multiline {
+			return "(?m:^)"
+		}
+		return "^"
+	case TextEnd:
+		if c.mod.multiline {
+			return "(?m:$)"
+		}
+		return "$"
+	case WordBoundary:
+		return "\\b"
+	default:
+		return "\\B"
+	}
+}
+
+// isEmpty returns true as an assertion does not consume any character.
+func (c *assertion) isEmpty() bool {
+	return true
+}
+
+func (c *assertion) nfa() *automata {
+	return charNfa(c)
+}
+
+func (c *assertion) match(rune) bool {
+	return false
+}
+
+func (c *assertion) spanSet() spanSet {
+	return nil
+}
+
+func (c *assertion) random(rng *rand.Rand) string {
+	return ""
+}
+
+func (c *assertion) modifier() *modifier {
+	return c.mod
+}
@@ -0,0 +1,124 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestTextStartEnd(t *testing.T) {
+	r := NewRegex("^abc$")
+	if !r.Match("abc") {
+		t.Error("'^abc$' did not match 'abc'")
+	}
+	if r.Match("abcabc") {
+		t.Error("'^abc$' matched 'abcabc'")
+	}
+	r = NewRegex("a^b")
+	if r.Match("ab") {
+		t.Error("'a^b' matched 'ab'")
+	}
+	r = NewRegex("a$b")
+	if r.Match("ab") {
+		t.Error("'a$b' matched 'ab'")
+	}
+	r = NewRegex("(a$|b)*")
+	if !r.Match("bba") {
+		t.Error("'(a$|b)*' did not match 'bba'")
+	}
+	if r.Match("bab") {
+		t.Error("'(a$|b)*' matched 'bab'")
+	}
+}
+
+func TestWordBoundary(t *testing.T) {
+	r := NewRegex("\\bab\\b")
+	if !r.Match("ab") {
+		t.Error("'\\bab\\b' did not match 'ab'")
+	}
+	r = NewRegex("a\\b b")
+	if !r.Match("a b") {
+		t.Error("'a\\b b' did not match 'a b'")
+	}
+	r = NewRegex("a\\bb")
+	if r.Match("ab") {
+		t.Error("'a\\bb' matched 'ab'")
+	}
+	r = NewRegex("\\w+\\b.*")
+	if !r.Match("abc, def") {
+		t.Error("'\\w+\\b.*' did not match 'abc, def'")
+	}
+	if !r.Match("abc") {
+		t.Error("'\\w+\\b.*' did not match 'abc'")
+	}
+}
+
+func TestNonWordBoundary(t *testing.T) {
+	r := NewRegex("a\\Bb")
+	if !r.Match("ab") {
+		t.Error("'a\\Bb' did not match 'ab'")
+	}
+	r = NewRegex("a\\B b")
+	if r.Match("a b") {
+		t.Error("'a\\B b' matched 'a b'")
+	}
+	r = NewRegex("\\B-")
+	if !r.Match("-") {
+		t.Error("'\\B-' did not match '-'")
+	}
+}
+
+func TestBoundaryMatchNext(t *testing.T) {
+	r := NewRegex("a\\b")
+	m := r.Matcher()
+	if m.MatchNext('a') != FullMatch {
+		t.Error("'a\\b' is not a full match of 'a'")
+	}
+	if m.MatchNext('b') != NoMatch {
+		t.Error("'a\\b' matched 'ab'")
+	}
+
+	r = NewRegex("\\bx")
+	m = r.Matcher()
+	m.ResetAfter('a')
+	if m.MatchNext('x') != NoMatch {
+		t.Error("'\\bx' matched 'x' after 'a'")
+	}
+	m.ResetAfter(' ')
+	if m.MatchNext('x') != FullMatch {
+		t.Error("'\\bx' did not match 'x' after ' '")
+	}
+
+	r = NewRegex("^x")
+	m = r.Matcher()
+	m.ResetAfter(' ')
+	if m.MatchNext('x') != NoMatch {
+		t.Error("'^x' matched 'x' after ' '")
+	}
+}
+
+func TestBoundaryClasses(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		matched    []string
+		notMatched []string
+	}{
+		{`\s$`, []string{" ", "\t", "\n"}, []string{"a", " a"}},
+		{`^\w`, []string{"a", "Z", "5", "_"}, []string{" ", "-"}},
+		{`\B\s`, []string{" ", "\t"}, []string{"a"}},
+		{`[ac]\b`, []string{"a", "c"}, []string{"b", "ab"}},
+	}
+	for _, test := range tests {
+		r := NewRegex(test.pattern)
+		for _, s := range test.matched {
+			if !r.Match(s) {
+				t.Errorf("%q did not match %q", test.pattern, s)
+			}
+		}
+		for _, s := range test.notMatched {
+			if r.Match(s) {
+				t.Errorf("%q matched %q", test.pattern, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Patterns can be built directly, instead of being parsed from a string, with the
+// constructors below, which create the same nodes as the parser. The characters of
+// the patterns built are taken literally and need no escaping. Patterns, whether
+// built or parsed, can be combined and are compiled by NewRegexFromPattern.
+//
+// The nodes of a pattern are visited with Walk, which describes each node with a
+// Node giving its operator and its operands.
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+)
+
+type (
+	// Op is the operator of a node of a pattern.
+	Op uint8
+
+	// Node describes a node of a pattern.
+	Node struct {
+		// Op is the operator of the node.
+		Op Op
+
+		// Pattern is the node itself.
+		Pattern Pattern
+
+		// Sub are the operands of the node, in order.
+		Sub []Pattern
+
+		// Min and Max are the counts of a repetition, with Max -1 for an unbounded one.
+		Min, Max int
+
+		// Group is the number of a capturing group.
+		Group int
+
+		// Boundary is the kind of position matched by an assertion.
+		Boundary Boundary
+
+		// Name is the name of a capturing group or of a word list, or the operator of a
+		// combination.
+		Name string
+
+		// Ranges are the characters matched by a character node, as pairs of the first
+		// and last characters of each range.
+		Ranges []rune
+	}
+)
+
+const (
+	OpChar        Op = iota // a character, a character set or a class
+	OpAssertion             // a boundary matcher: ^, $, \b or \B
+	OpList                  // a word list (:name)
+	OpSequence              // a sequence of patterns, matching the empty string if it has none
+	OpChoice                // a choice between two patterns
+	OpOptional              // an optional pattern (x?)
+	OpStar                  // zero or more repetitions (x*)
+	OpPlus                  // one or more repetitions (x+)
+	OpRepeat                // a counted repetition (x{m,n})
+	OpGroup                 // a capturing group
+	OpCombination           // an intersection (&), difference (-) or complement (~) of regular expressions
+)
+
+// Literal returns the pattern matching the string s.
+func Literal(s string) Pattern {
+	var chars []Pattern
+	for _, c := range s {
+		chars = append(chars, &singleChar{&modifier{}, c})
+	}
+	if len(chars) == 1 {
+		return chars[0]
+	}
+	return &sequence{chars}
+}
+
+// Class returns the pattern matching a character in one of the ranges, which are
+// given as pairs of their first and last characters: Class('a', 'z', '_', '_')
+// matches a lowercase letter or an underscore. The last range is a single character
+// if the number of characters is odd.
+func Class(ranges ...rune) Pattern {
+	return &charSet{mod: &modifier{}, span: spansOfRanges(ranges)}
+}
+
+// NotClass returns the pattern matching a character which is not in any of the ranges,
+// given as in Class.
+func NotClass(ranges ...rune) Pattern {
+	spans := spansOfRanges(ranges).invertUnicode()
+	if spans == nil {
+		spans = spanSet{}
+	}
+	return &charSet{mod: &modifier{}, span: spans}
+}
+
+func spansOfRanges(ranges []rune) spanSet {
+	spans := spanSet{}
+	for i := 0; i < len(ranges); i += 2 {
+		from, to := ranges[i], ranges[i]
+		if i+1 < len(ranges) {
+			to = ranges[i+1]
+		}
+		spans = append(spans, span{min(from, to), max(from, to)})
+	}
+	return spans.compact()
+}
+
+// Seq returns the sequence of the patterns, which matches the empty string if there
+// are none.
+func Seq(patterns ...Pattern) Pattern {
+	return &sequence{patterns}
+}
+
+// Alt returns the choice between the patterns, which matches no string if there are none.
+func Alt(patterns ...Pattern) Pattern {
+	if len(patterns) == 0 {
+		return &charSet{mod: &modifier{}, span: spanSet{}}
+	}
+	p := patterns[len(patterns)-1]
+	for i := len(patterns) - 2; i >= 0; i-- {
+		p = &choice{patterns[i], p}
+	}
+	return p
+}
+
+// Optional returns the pattern matching p or the empty string (p?).
+func Optional(p Pattern) Pattern {
+	return &zeroOrOne{p}
+}
+
+// Star returns the pattern matching zero or more repetitions of p (p*).
+func Star(p Pattern) Pattern {
+	return &zeroOrMore{p}
+}
+
+// Plus returns the pattern matching one or more repetitions of p (p+).
+func Plus(p Pattern) Pattern {
+	return &oneOrMore{p}
+}
+
+// Repeat returns the pattern matching between min and max repetitions of p (p{min,max}),
+// or at least min repetitions if max is negative. It returns an error if the counts are
+// not valid, or if the automata of the repetition would be too large, as for a parsed
+// pattern.
+func Repeat(p Pattern, min, max int) (Pattern, error) {
+	unbounded := max < 0
+	if min < 0 || (!unbounded && min > max) || min > maxRepeat || max > maxRepeat {
+		return nil, errors.New("regex: invalid repetition counts " + strconv.Itoa(min) + ", " + strconv.Itoa(max))
+	}
+	r := &repeat{p, min, max, unbounded}
+	if nfaSize(r) > maxStates {
+		return nil, errors.New("regex: repetition with an automaton of more than " + strconv.Itoa(maxStates) + " states")
+	}
+	return r, nil
+}
+
+// Capture returns a capturing group of p, which is numbered when compiled. (Group is
+// the text captured by a group in a match.)
+func Capture(p Pattern) Pattern {
+	return &captureGroup{p, 0, ""}
+}
+
+// NamedCapture returns a capturing group of p with the name.
+func NamedCapture(name string, p Pattern) Pattern {
+	return &captureGroup{p, 0, name}
+}
+
+// NewRegexFromPattern compiles a pattern, which can be built with the constructors or
+// combine parsed patterns, into a regular expression. Its capturing groups are
+// numbered from 1 in the order of their opening brackets in the string of the pattern.
+func NewRegexFromPattern(p Pattern) *Regex {
+	groups := 0
+	p = numbered(p, &groups)
+	return compile(p, groups, options{})
+}
+
+// numbered returns a copy of the pattern with its groups numbered in order, after
+// the given number of groups.
+func numbered(p Pattern, groups *int) Pattern {
+	switch p := p.(type) {
+	case *choice:
+		left := numbered(p.left, groups)
+		return &choice{left, numbered(p.right, groups)}
+	case *sequence:
+		s := make([]Pattern, len(p.sequence))
+		for i, e := range p.sequence {
+			s[i] = numbered(e, groups)
+		}
+		return &sequence{s}
+	case *zeroOrOne:
+		return &zeroOrOne{numbered(p.opt, groups)}
+	case *zeroOrMore:
+		return &zeroOrMore{numbered(p.re, groups)}
+	case *oneOrMore:
+		return &oneOrMore{numbered(p.re, groups)}
+	case *repeat:
+		return &repeat{numbered(p.re, groups), p.min, p.max, p.unbounded}
+	case *captureGroup:
+		*groups++
+		group := *groups
+		return &captureGroup{numbered(p.re, groups), group, p.name}
+	default:
+		return p
+	}
+}
+
+// Inspect returns the description of the node at the root of the pattern.
+func Inspect(p Pattern) Node {
+	n := Node{Pattern: p}
+	switch p := p.(type) {
+	case *choice:
+		n.Op, n.Sub = OpChoice, []Pattern{p.left, p.right}
+	case *sequence:
+		n.Op, n.Sub = OpSequence, p.sequence
+	case *zeroOrOne:
+		n.Op, n.Sub = OpOptional, []Pattern{p.opt}
+	case *zeroOrMore:
+		n.Op, n.Sub = OpStar, []Pattern{p.re}
+	case *oneOrMore:
+		n.Op, n.Sub = OpPlus, []Pattern{p.re}
+	case *repeat:
+		n.Op, n.Sub, n.Min, n.Max = OpRepeat, []Pattern{p.re}, p.min, p.max
+		if p.unbounded {
+			n.Max = -1
+		}
+	case *captureGroup:
+		n.Op, n.Sub, n.Group, n.Name = OpGroup, []Pattern{p.re}, p.group, p.name
+	case *combination:
+		n.Op, n.Sub, n.Name = OpCombination, p.operands, p.op
+	case *assertion:
+		n.Op, n.Boundary = OpAssertion, p.kind
+	case *inList:
+		n.Op, n.Name = OpList, p.list
+	case char:
+		n.Op = OpChar
+		for _, s := range slices.Clone(p.spanSet()).compact() {
+			n.Ranges = append(n.Ranges, s.from, s.to)
+		}
+	}
+	return n
+}
+
+// Walk visits the nodes of the pattern in depth-first order, calling visit with the
+// description of each node before visiting its operands. The operands of a node are
+// not visited if visit returns false for the node.
+func Walk(p Pattern, visit func(n Node) bool) {
+	n := Inspect(p)
+	if visit(n) {
+		for _, s := range n.Sub {
+			Walk(s, visit)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	repeat := func(p Pattern, min, max int) Pattern {
+		r, err := Repeat(p, min, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	tests := []struct {
+		built  Pattern
+		parsed string
+	}{
+		{Literal("a.b"), "a\\.b"},
+		{Literal(""), ""},
+		{Seq(Class('a', 'z', '_'), Star(Class('a', 'z', '0', '9', '_'))), "[a-z_][a-z0-9_]*"},
+		{Alt(Literal("if"), Literal("else"), Literal("for")), "if|else|for"},
+		{Seq(Literal("x"), Plus(Alt(Literal("ab"), Literal("c")))), "x(ab|c)+"},
+		{Seq(Optional(Literal("-")), repeat(Class('0', '9'), 1, 3)), "-?[0-9]{1,3}"},
+		{repeat(Literal("ab"), 2, -1), "(ab){2,}"},
+		{NotClass('\n'), "[^\\n]"},
+		{Seq(Literal("["), NotClass(']'), Literal("]")), "\\[[^\\]]\\]"},
+		{Alt(), "[^\\x00-\\x{10ffff}]"},
+	}
+	for _, test := range tests {
+		built := NewRegexFromPattern(test.built)
+		if equivalent, s := Equivalent(built, MustCompile(test.parsed)); !equivalent {
+			t.Errorf("%s differs from %q on %q", test.built, test.parsed, s)
+		}
+		if equivalent, s := Equivalent(built, MustCompile(test.built.String())); !equivalent {
+			t.Errorf("%s differs from its string %q on %q", test.built, test.built.String(), s)
+		}
+	}
+}
+
+func TestBuilderGroups(t *testing.T) {
+	digits := Plus(Class('0', '9'))
+	p := Seq(Capture(digits), Literal("-"), NamedCapture("month", Capture(digits)))
+	r := NewRegexFromPattern(p)
+	if !slices.Equal(r.SubexpNames(), []string{"", "", "month", ""}) {
+		t.Errorf("unexpected groups %q", r.SubexpNames())
+	}
+	f := r.Find("on 2024-05")
+	if f == nil || f.Groups[1].Text != "2024" || f.Groups[2].Text != "05" || f.Groups[3].Text != "05" {
+		t.Errorf("unexpected match %v", f)
+	}
+
+	// parsed patterns are combined, with their groups numbered again
+	date := MustCompile("(\\d+)-(\\d+)").Pattern
+	r = NewRegexFromPattern(Seq(date, Literal(" "), date))
+	f = r.Find("2024-05 2025-11")
+	if f == nil || len(f.Groups) != 5 || f.Groups[3].Text != "2025" || f.Groups[4].Text != "11" {
+		t.Errorf("unexpected match %v", f)
+	}
+}
+
+func TestBuilderMembership(t *testing.T) {
+	tests := []struct {
+		built    Pattern
+		matched  []string
+		rejected []string
+	}{
+		{Optional(Seq(Literal("a"), Star(Literal("b")))), []string{"", "a", "abb"}, []string{"b", "bb"}},
+		{Star(Seq(Literal("a"), Star(Literal("b")))), []string{"", "aab", "abab"}, []string{"b", "ba"}},
+		{Seq(Literal("x"), Optional(Seq(Literal("a"), Plus(Literal("b"))))), []string{"x", "xab"}, []string{"xa", "xb"}},
+		{MustCompile("(?i:ab*)?").Pattern, []string{"", "A", "aBb"}, []string{"b", "B"}},
+	}
+	for _, test := range tests {
+		r := NewRegexFromPattern(test.built)
+		for _, s := range test.matched {
+			if !r.Match(s) {
+				t.Errorf("%s: %q not matched", test.built, s)
+			}
+		}
+		for _, s := range test.rejected {
+			if r.Match(s) {
+				t.Errorf("%s: %q matched", test.built, s)
+			}
+		}
+	}
+}
+
+func TestBuilderRepeatErrors(t *testing.T) {
+	for _, counts := range [][]int{{-1, 2}, {3, 2}, {0, maxRepeat + 1}} {
+		if p, err := Repeat(Literal("a"), counts[0], counts[1]); err == nil {
+			t.Errorf("Repeat with counts %v returned %s instead of an error", counts, p)
+		}
+	}
+	if _, err := Repeat(Literal("abc"), maxRepeat, maxRepeat); err == nil {
+		t.Errorf("Repeat of an automaton too large did not return an error")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	r := MustCompile("(?<year>\\d{4})-(a|[bc]+)?$")
+	var ops []Op
+	var nodes []Node
+	Walk(r.Pattern, func(n Node) bool {
+		ops = append(ops, n.Op)
+		nodes = append(nodes, n)
+		return true
+	})
+	expected := []Op{OpSequence, OpGroup, OpSequence, OpRepeat, OpChar, OpChar, OpOptional, OpGroup,
+		OpChoice, OpSequence, OpChar, OpSequence, OpPlus, OpChar, OpAssertion}
+	if !slices.Equal(ops, expected) {
+		t.Errorf("expected %v, got %v", expected, ops)
+	}
+	if nodes[1].Group != 1 || nodes[1].Name != "year" {
+		t.Errorf("unexpected group %+v", nodes[1])
+	}
+	if nodes[3].Min != 4 || nodes[3].Max != 4 {
+		t.Errorf("unexpected repetition %+v", nodes[3])
+	}
+	if !slices.Equal(nodes[4].Ranges, []rune{'0', '9'}) {
+		t.Errorf("unexpected ranges %q", nodes[4].Ranges)
+	}
+	if last := nodes[len(nodes)-1]; last.Boundary != TextEnd {
+		t.Errorf("unexpected assertion %+v", last)
+	}
+	var boundaries []Boundary
+	Walk(MustCompile("^\\ba\\B$").Pattern, func(n Node) bool {
+		if n.Op == OpAssertion {
+			boundaries = append(boundaries, n.Boundary)
+		}
+		return true
+	})
+	if expected := []Boundary{TextStart, WordBoundary, NonWordBoundary, TextEnd}; !slices.Equal(boundaries, expected) {
+		t.Errorf("expected assertions %v, got %v", expected, boundaries)
+	}
+
+	// operands are skipped when visit returns false
+	count := 0
+	Walk(r.Pattern, func(n Node) bool {
+		count++
+		return n.Op != OpGroup
+	})
+	if count != 6 {
+		t.Errorf("expected 6 nodes visited, got %d", count)
+	}
+}
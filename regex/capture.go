@@ -0,0 +1,302 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Capturing groups are located with a tagged NFA (TNFA): the NFA of a group is
+// enclosed between two tag transitions which record the positions in the input
+// where the group starts and ends. The DFA ignores the tags and decides whether
+// the input matches, while the matcher simulates the TNFA, one character at a
+// time alongside the DFA, to find the positions of the groups. This keeps the
+// prefix matching property of the matcher.
+//
+// The simulation follows a set of threads (paths through the TNFA), each one
+// carrying the positions recorded on its path. Threads are kept in order of
+// priority, which is given by the order in which the empty transitions were
+// created when constructing the NFA: this prefers the left alternative of a
+// choice and more repetitions of a closure, as backtracking matchers do. Only
+// the highest priority thread reaching each state is kept, which ensures that
+// repeated groups capture their last iteration. The groups of a match are those
+// of the highest priority thread reaching the final state.
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+type (
+	// Group is the text captured by a capturing group in a match.
+	Group struct {
+		// Start is the byte offset of the start of the captured text, or -1 if the
+		// group did not participate in the match.
+		Start int
+
+		// End is the byte offset just after the end of the captured text, or -1.
+		End int
+
+		// Text is the text captured.
+		Text string
+	}
+
+	// tag is an empty transition which records the current position in the input
+	// as the start (open) or the end of a group. Tags carry the name of their group
+	// through the construction of the automata.
+	tag struct {
+		order uint64
+		group int
+		name  string
+		open  bool
+	}
+
+	transition struct {
+		char char
+		to   state
+	}
+
+	// tnfa is the tagged NFA of a regular expression with capturing groups. The
+	// empty transitions of each state are sorted by priority.
+	tnfa struct {
+		start  state
+		final  state
+		groups int
+		names  []string // names[g] is the name of group g, empty if unnamed
+		empty  map[state][]transition
+		chars  map[state][]transition
+	}
+
+	// thread is a path through the TNFA, with the positions of the groups recorded
+	// along the path: captures[2*g] and captures[2*g+1] are the start and end of group g.
+	thread struct {
+		state    state
+		captures []int
+	}
+)
+
+// order is incremented for every empty transition (and tag) created, giving their priority.
+var order atomic.Uint64
+
+// epsilon returns a new empty transition with a lower priority than all previous ones.
+func epsilon() *empty {
+	return &empty{order.Add(1)}
+}
+
+// priority returns the priority of an empty transition; lower values have higher priority.
+func priority(c char) uint64 {
+	switch c := c.(type) {
+	case *empty:
+		return c.order
+	case *tag:
+		return c.order
+	}
+	return 0
+}
+
+// newTnfa creates the TNFA for an NFA containing the tags of the given number of groups.
+func newTnfa(auto *automata, groups int) *tnfa {
+	n := &tnfa{
+		start:  auto.start,
+		final:  auto.final[0],
+		groups: groups,
+		names:  make([]string, groups+1),
+		empty:  map[state][]transition{},
+		chars:  map[state][]transition{},
+	}
+	for s, trans := range auto.Trans {
+		for c, t := range trans {
+			if g, ok := c.(*tag); ok && g.open {
+				n.names[g.group] = g.name
+			}
+			if c.isEmpty() {
+				n.empty[s] = append(n.empty[s], transition{c, t})
+			} else {
+				n.chars[s] = append(n.chars[s], transition{c, t})
+			}
+		}
+		slices.SortFunc(n.empty[s], func(a, b transition) int {
+			return cmp.Compare(priority(a.char), priority(b.char))
+		})
+	}
+	return n
+}
+
+// begin returns the single thread at the start of the TNFA.
+func (n *tnfa) begin() []thread {
+	captures := slices.Repeat([]int{-1}, 2*(n.groups+1))
+	captures[0] = 0
+	return []thread{{n.start, captures}}
+}
+
+// closure returns the threads reachable from the threads through empty transitions
+// at position pos in the input, between characters in the prev and next contexts.
+// Threads are returned in order of priority, with only the first one to reach a state.
+func (n *tnfa) closure(threads []thread, pos int, prev, next context) []thread {
+	var result []thread
+	visited := set[state]{}
+	var follow func(t thread)
+	follow = func(t thread) {
+		if visited[t.state] {
+			return
+		}
+		visited[t.state] = true
+		result = append(result, t)
+		for _, e := range n.empty[t.state] {
+			switch c := e.char.(type) {
+			case *assertion:
+				if c.holds(prev, next) {
+					follow(thread{e.to, t.captures})
+				}
+			case *tag:
+				captures := slices.Clone(t.captures)
+				if c.open {
+					captures[2*c.group] = pos
+				} else {
+					captures[2*c.group+1] = pos
+				}
+				follow(thread{e.to, captures})
+			default:
+				follow(thread{e.to, t.captures})
+			}
+		}
+	}
+	for _, t := range threads {
+		follow(t)
+	}
+	return result
+}
+
+// step returns the threads after consuming the character r at position pos.
+func (n *tnfa) step(threads []thread, r rune, pos int, prev context) []thread {
+	var result []thread
+	visited := set[state]{}
+	for _, t := range n.closure(threads, pos, prev, contextOf(r)) {
+		for _, e := range n.chars[t.state] {
+			if !visited[e.to] && e.char.match(r) {
+				visited[e.to] = true
+				result = append(result, thread{e.to, t.captures})
+			}
+		}
+	}
+	return result
+}
+
+// captures returns the positions of the groups of the highest priority thread reaching
+// the final state when the input ends at pos, followed by a character in the next context.
+// It returns nil if no thread reaches the final state.
+func (n *tnfa) captures(threads []thread, pos int, prev, next context) []int {
+	for _, t := range n.closure(threads, pos, prev, next) {
+		if t.state == n.final {
+			captures := slices.Clone(t.captures)
+			captures[1] = pos
+			return captures
+		}
+	}
+	return nil
+}
+
+// groupsOf returns the groups captured in text with their positions shifted by offset.
+func groupsOf(text string, offset int, captures []int) []Group {
+	groups := make([]Group, len(captures)/2)
+	for g := range groups {
+		start, end := captures[2*g], captures[2*g+1]
+		if start >= 0 && end >= start {
+			groups[g] = Group{offset + start, offset + end, text[start:end]}
+		} else {
+			groups[g] = Group{-1, -1, ""}
+		}
+	}
+	return groups
+}
+
+// Groups returns the groups captured in the last full match of the matcher, with group
+// 0 being the whole match, or nil if there has not been any full match since the last reset.
+func (m *Matcher) Groups() []Group {
+	if m.captures == nil {
+		return nil
+	}
+	return groupsOf(m.FullMatch.String(), 0, m.captures)
+}
+
+// Group returns the group with the given name in the last full match of the matcher,
+// or nil if there is no such group or no full match since the last reset. When several
+// groups have the same name, the first one which participated in the match is returned.
+func (m *Matcher) Group(name string) *Group {
+	groups := m.Groups()
+	if groups == nil || name == "" {
+		return nil
+	}
+	var found *Group
+	for g, n := range m.Compiled.tnfa.names {
+		if n == name {
+			if found == nil || found.Start == -1 {
+				found = &groups[g]
+			}
+		}
+	}
+	return found
+}
+
+// begin starts the simulation of the TNFA after a reset.
+func (m *Matcher) begin(prev context) {
+	m.pos = 0
+	m.prev = prev
+	m.captures = nil
+	if m.Compiled.tnfa != nil {
+		m.threads = m.Compiled.tnfa.begin()
+	}
+	if m.final(atEdge) {
+		m.captures = m.capturesBefore(atEdge)
+	}
+}
+
+// advance moves the simulation of the TNFA over the character r.
+func (m *Matcher) advance(r rune) {
+	if m.Compiled.tnfa != nil {
+		m.threads = m.Compiled.tnfa.step(m.threads, r, m.pos, m.prev)
+	}
+	m.pos += utf8.RuneLen(r)
+	m.prev = contextOf(r)
+	if m.LastMatch == FullMatch {
+		m.captures = m.capturesBefore(atEdge)
+	}
+}
+
+// capturesBefore returns the positions of the groups if the input matched so far is
+// followed by a character in the next context.
+func (m *Matcher) capturesBefore(next context) []int {
+	if m.Compiled.tnfa == nil {
+		return []int{0, m.pos}
+	}
+	return m.Compiled.tnfa.captures(m.threads, m.pos, m.prev, next)
+}
+
+//------------- Tags -------------//
+
+func (c *tag) String() string {
+	return ""
+}
+
+func (c *tag) isEmpty() bool {
+	return true
+}
+
+func (c *tag) nfa() *automata {
+	return charNfa(c)
+}
+
+func (c *tag) match(rune) bool {
+	return false
+}
+
+func (c *tag) spanSet() spanSet {
+	return nil
+}
+
+func (c *tag) random(rng *rand.Rand) string {
+	return ""
+}
+
+func (c *tag) modifier() *modifier {
+	return nil
+}
@@ -0,0 +1,92 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRepeatedGroup(t *testing.T) {
+	m := NewRegex("(a|b)+").Matcher()
+	m.Match("abab")
+	if groups := m.Groups(); groups[1].Text != "b" || groups[1].Start != 3 {
+		t.Error("'(a|b)+' did not capture the last iteration in 'abab':", groups)
+	}
+}
+
+func TestAlternativeGroups(t *testing.T) {
+	m := NewRegex("(ab)c|(a)(bd)").Matcher()
+	m.Match("abd")
+	groups := m.Groups()
+	if groups[1].Start != -1 || groups[2].Text != "a" || groups[3].Text != "bd" {
+		t.Error("'(ab)c|(a)(bd)' did not capture groups 2 and 3 in 'abd':", groups)
+	}
+}
+
+func TestNestedGroups(t *testing.T) {
+	m := NewRegex("((a)(b)?)+c").Matcher()
+	m.Match("abac")
+	groups := m.Groups()
+	// group 3 keeps the text captured in the first iteration, as in Go regexp
+	if groups[0].Text != "abac" || groups[1].Text != "a" || groups[2].Start != 2 || groups[3].Start != 1 {
+		t.Error("'((a)(b)?)+c' did not capture the groups of the last iteration in 'abac':", groups)
+	}
+}
+
+func TestGroupsOfPrefix(t *testing.T) {
+	m := NewRegex("(a+)(b*)").Matcher()
+	m.MatchNext('a')
+	m.MatchNext('a')
+	if groups := m.Groups(); groups[1].Text != "aa" || groups[2].Text != "" || groups[2].Start != 2 {
+		t.Error("'(a+)(b*)' did not capture the groups of 'aa':", m.Groups())
+	}
+	m.MatchNext('b')
+	m.MatchNext('c')
+	if groups := m.Groups(); groups[0].Text != "aab" || groups[2].Text != "b" {
+		t.Error("'(a+)(b*)' did not keep the groups of the last full match 'aab':", groups)
+	}
+}
+
+func TestGroupsWithBoundary(t *testing.T) {
+	f := NewRegex("(\\w+)\\b(.*)").Find("ab cd")
+	if f == nil || f.Groups[1].Text != "ab" || f.Groups[2].Text != " cd" {
+		t.Error("'(\\w+)\\b(.*)' did not capture the groups of 'ab cd':", f)
+	}
+}
+
+func TestNamedGroups(t *testing.T) {
+	r := NewRegex("(?P<area>\\d+)-(\\d+)-(?<number>\\d+)")
+	names := r.SubexpNames()
+	if !slices.Equal(names, []string{"", "area", "", "number"}) {
+		t.Error("unexpected group names", names)
+	}
+	m := r.Matcher()
+	m.Match("230-555-1234")
+	if g := m.Group("area"); g == nil || g.Text != "230" {
+		t.Error("group 'area' was not captured in '230-555-1234':", g)
+	}
+	if g := m.Group("number"); g == nil || g.Text != "1234" || g.Start != 8 {
+		t.Error("group 'number' was not captured in '230-555-1234':", g)
+	}
+	if g := m.Group("unknown"); g != nil {
+		t.Error("unknown group was captured:", g)
+	}
+	if r.SubexpIndex("number") != 3 || r.SubexpIndex("unknown") != -1 {
+		t.Error("wrong group indices for names")
+	}
+}
+
+func TestSameNamedGroups(t *testing.T) {
+	m := NewRegex("(?<d>a)b|c(?<d>d)").Matcher()
+	m.Match("cd")
+	if g := m.Group("d"); g == nil || g.Text != "d" {
+		t.Error("group 'd' was not captured in 'cd':", g)
+	}
+}
+
+func TestNoGroupNames(t *testing.T) {
+	if names := NewRegex("abc").SubexpNames(); !slices.Equal(names, []string{""}) {
+		t.Error("unexpected group names", names)
+	}
+}
@@ -9,61 +9,52 @@ package regex
 
 import (
 	"container/list"
-	"embed"
-	"math"
 	"math/rand"
+	"slices"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
-//go:embed lists/*
-var lists embed.FS
-
 // -------------Character and character sets parsing-------------//
 type (
 	char interface {
 		match(c rune) bool
 		isEmpty() bool
 
-		groups() *list.List // [int]
-		setGroups(g *list.List)
-
 		modifier() *modifier
 
 		// spanSet returns the range of characters that can be matched by this char.
 		spanSet() spanSet
 
-		random() string
+		// random returns a random string matched by this char, using the random number
+		// generator (see intn).
+		random(rng *rand.Rand) string
 
 		Pattern
 	}
 
-	empty struct{ _ uint8 }
+	empty struct{ order uint64 } // order gives the priority of empty transitions in the NFA
 
 	anyChar struct {
-		mod   *modifier
-		group list.List
+		mod *modifier
 	}
 
 	singleChar struct {
-		mod   *modifier
-		char  rune
-		group list.List
+		mod  *modifier
+		char rune
 	}
 
 	charRange struct {
-		mod   *modifier
-		from  rune
-		to    rune
-		group list.List
+		mod  *modifier
+		from rune
+		to   rune
 	}
 
 	charSet struct {
 		mod     *modifier
 		exclude bool
 		sets    list.List // [char]
-		group   list.List // [int]
 
 		span spanSet
 	}
@@ -81,9 +72,8 @@ type (
 	inList struct {
 		mod     *modifier
 		list    string
-		words   []string
 		convert conversion
-		group   list.List // [int]
+		whole   bool // a single transition generating whole words, instead of a trie matching them
 	}
 )
 
@@ -97,12 +87,6 @@ func (c *empty) isEmpty() bool {
 	return true
 }
 
-func (c *empty) groups() *list.List {
-	return nil
-}
-
-func (c *empty) setGroups(g *list.List) {}
-
 func (c *empty) nfa() *automata {
 	return nil
 }
@@ -115,7 +99,7 @@ func (c *empty) spanSet() spanSet {
 	return nil
 }
 
-func (c *empty) random() string {
+func (c *empty) random(rng *rand.Rand) string {
 	return ""
 }
 
@@ -126,40 +110,39 @@ func (c *empty) modifier() *modifier {
 //------------- Any character -------------//
 
 func (c *anyChar) String() string {
+	if c.mod.dotAll {
+		return "(?s:.)"
+	}
 	return "."
-	//return ".:" + label(c.groups())
 }
 
 func (c *anyChar) isEmpty() bool {
 	return false
 }
 
-func (c *anyChar) groups() *list.List {
-	return &c.group
-}
-
-func (c *anyChar) setGroups(g *list.List) {
-	c.group = *g
-}
-
 func (c *anyChar) nfa() *automata {
 	return charNfa(c)
 }
 
-func (c *anyChar) match(rune) bool {
-	return true
+// match returns true for any character except newline, which is only matched in
+// dot-all mode (?s).
+func (c *anyChar) match(r rune) bool {
+	return r != '\n' || c.mod.dotAll
 }
 
 func (c *anyChar) spanSet() spanSet {
 	//if c.mod.unicode {
-	return allUnicode
+	if c.mod.dotAll {
+		return allUnicode
+	}
+	return allButNewline
 	//} else {
 	//    return asciiPrintable
 	//}
 }
 
-func (c *anyChar) random() string {
-	return string(c.spanSet().random())
+func (c *anyChar) random(rng *rand.Rand) string {
+	return string(c.spanSet().random(rng))
 }
 
 func (c *anyChar) modifier() *modifier {
@@ -168,23 +151,19 @@ func (c *anyChar) modifier() *modifier {
 
 //------------- A single character match -------------//
 
+// String returns the character escaped, or the set of its case variants in
+// case-insensitive mode.
 func (c *singleChar) String() string {
-	return string(c.char)
-	//return string(c.char) + ":" + label(c.groups())
+	if spans := c.spanSet(); len(spans) > 1 {
+		return spans.String()
+	}
+	return escapeChar(c.char, false)
 }
 
 func (c *singleChar) isEmpty() bool {
 	return false
 }
 
-func (c *singleChar) groups() *list.List {
-	return &c.group
-}
-
-func (c *singleChar) setGroups(g *list.List) {
-	c.group = *g
-}
-
 func (c *singleChar) nfa() *automata {
 	return charNfa(c)
 }
@@ -213,8 +192,8 @@ func (c *singleChar) spanSet() spanSet {
 	}
 }
 
-func (c *singleChar) random() string {
-	return string(c.spanSet().random())
+func (c *singleChar) random(rng *rand.Rand) string {
+	return string(c.spanSet().random(rng))
 }
 
 func (c *singleChar) modifier() *modifier {
@@ -224,25 +203,13 @@ func (c *singleChar) modifier() *modifier {
 //------------- A character range match -------------//
 
 func (c *charRange) String() string {
-	if c.to < math.MaxUint8 {
-		return string(c.from) + "-" + string(c.to)
-	} else {
-		return string(c.from) + "-"
-	}
+	return c.spanSet().String()
 }
 
 func (c *charRange) isEmpty() bool {
 	return false
 }
 
-func (c *charRange) groups() *list.List {
-	return &c.group
-}
-
-func (c *charRange) setGroups(g *list.List) {
-	c.group = *g
-}
-
 func (c *charRange) nfa() *automata {
 	return charNfa(c)
 }
@@ -282,8 +249,8 @@ func (c *charRange) spanSet() spanSet {
 	}
 }
 
-func (c *charRange) random() string {
-	return string(c.spanSet().random())
+func (c *charRange) random(rng *rand.Rand) string {
+	return string(c.spanSet().random(rng))
 }
 
 func (c *charRange) modifier() *modifier {
@@ -292,34 +259,64 @@ func (c *charRange) modifier() *modifier {
 
 //------------- A character set combines different characters (and ranges) -------------//
 
+// String returns the set with its characters, sorted and combined, unless it contains
+// named classes, which are kept with the other characters of the set.
 func (c *charSet) String() string {
-	ret := "["
+	if c.plain() {
+		spans := c.spanSet()
+		if len(spans) == 1 && spans[0].from == spans[0].to {
+			return escapeChar(spans[0].from, false)
+		}
+		return spans.String()
+	}
+	var s strings.Builder
+	s.WriteRune('[')
 	if c.exclude {
-		ret += "^"
+		s.WriteRune('^')
 	}
-	first := true
 	for cs := c.sets.Front(); cs != nil; cs = cs.Next() {
-		if first {
-			first = false
-		} else {
-			ret += "|"
-		}
-		ret += cs.Value.(char).String()
+		s.WriteString(setContent(cs.Value.(char)))
 	}
-	ret += "]"
-	return ret
+	s.WriteRune(']')
+	return s.String()
 }
 
-func (c *charSet) isEmpty() bool {
-	return false
+// plain returns true if the set does not contain named classes.
+func (c *charSet) plain() bool {
+	for cs := c.sets.Front(); cs != nil; cs = cs.Next() {
+		switch m := cs.Value.(type) {
+		case *class:
+			return false
+		case *charSet:
+			if !m.plain() {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-func (c *charSet) groups() *list.List {
-	return &c.group
+// setContent returns a character as written inside a character set.
+func setContent(c char) string {
+	switch c := c.(type) {
+	case *class:
+		if !c.mod.caseInsensitive {
+			return c.content()
+		}
+	case *charSet:
+		if !c.exclude {
+			var s strings.Builder
+			for cs := c.sets.Front(); cs != nil; cs = cs.Next() {
+				s.WriteString(setContent(cs.Value.(char)))
+			}
+			return s.String()
+		}
+	}
+	return slices.Clone(c.spanSet()).compact().content()
 }
 
-func (c *charSet) setGroups(g *list.List) {
-	c.group = *g
+func (c *charSet) isEmpty() bool {
+	return false
 }
 
 func (c *charSet) nfa() *automata {
@@ -355,12 +352,16 @@ func (c *charSet) spanSet() spanSet {
 		} else {
 			c.span = c.span.compact()
 		}
+		if c.span == nil {
+			// an empty set matches no character, unlike characters without spans (lists)
+			c.span = spanSet{}
+		}
 	}
 	return c.span
 }
 
-func (c *charSet) random() string {
-	return string(c.spanSet().random())
+func (c *charSet) random(rng *rand.Rand) string {
+	return string(c.spanSet().random(rng))
 }
 
 func (c *charSet) modifier() *modifier {
@@ -370,6 +371,15 @@ func (c *charSet) modifier() *modifier {
 //----------------- In list ----------------//
 
 func (c *inList) String() string {
+	conversions := ""
+	for i, set := range []bool{c.convert.lower, c.convert.upper, c.convert.title, c.convert.singleSpace, c.convert.trim} {
+		if set {
+			conversions += string("lutsm"[i])
+		}
+	}
+	if conversions != "" {
+		return "(:" + c.list + ":" + conversions + ")"
+	}
 	return "(:" + c.list + ")"
 }
 
@@ -377,16 +387,44 @@ func (c *inList) isEmpty() bool {
 	return false
 }
 
-func (c *inList) groups() *list.List {
-	return &c.group
-}
-
-func (c *inList) setGroups(g *list.List) {
-	c.group = *g
-}
-
+// nfa returns a trie of the words of the list, after their conversions, with a
+// transition on each of their characters, so that the list is matched as a choice
+// between its words. Words share the transitions of their common prefixes and suffixes,
+// which are compared ignoring case in case-insensitive mode (see wordTrie). An unknown
+// list matches nothing. For generation, the list is a single transition generating a
+// whole word instead (see generationDfa).
 func (c *inList) nfa() *automata {
-	return charNfa(c)
+	if c.whole {
+		return charNfa(c)
+	}
+	a := &automata{
+		Trans: make(transitions),
+		start: &stateObj{},
+		final: []state{&stateObj{}},
+	}
+	l, ok := lookupList(c.list)
+	if !ok {
+		return a
+	}
+	t := l.trie(c.converted, trieKey{c.convert, c.mod.caseInsensitive})
+	states := make([]state, len(t.edges))
+	for i := range states {
+		states[i] = &stateObj{}
+	}
+	a.start = states[t.root]
+	for i, edges := range t.edges {
+		trans := make(map[char]state, len(edges)+1)
+		for _, e := range edges {
+			trans[&singleChar{c.mod, e.char}] = states[e.to]
+		}
+		if t.end[i] {
+			trans[epsilon()] = a.final[0]
+		}
+		if len(trans) > 0 {
+			a.Trans[states[i]] = trans
+		}
+	}
+	return a
 }
 
 func (c *inList) match(char rune) bool {
@@ -397,19 +435,17 @@ func (c *inList) spanSet() spanSet {
 	return nil
 }
 
-func (c *inList) random() string {
-	if c.words == nil {
-		bytes, err := lists.ReadFile("lists/" + c.list)
-		if err != nil {
-			panic(err)
-		}
-		content := string(bytes)
-		c.words = strings.Split(content, "\n")
-		for i, w := range c.words {
-			c.words[i] = strings.TrimSpace(w)
-		}
+func (c *inList) random(rng *rand.Rand) string {
+	l, ok := lookupList(c.list)
+	if !ok {
+		// unknown lists are only accepted by lenient parsing
+		return ""
 	}
-	word := c.words[rand.Intn(len(c.words))]
+	return c.converted(l.random(rng))
+}
+
+// converted returns the word with the conversions of the list applied.
+func (c *inList) converted(word string) string {
 	if c.convert.trim {
 		word = strings.TrimSpace(word)
 	}
@@ -420,7 +456,7 @@ func (c *inList) random() string {
 		word = strings.ToLower(word)
 	} else if c.convert.upper {
 		word = strings.ToUpper(word)
-	} else if c.convert.title {
+	} else if c.convert.title && word != "" {
 		r, s := utf8.DecodeRuneInString(word)
 		word = string(unicode.ToUpper(r)) + strings.ToLower(word[s:])
 	}
@@ -0,0 +1,176 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Named character classes: the Unicode categories, scripts and properties of the
+// unicode package (\p{Greek}, \pL, \P{Nd}) and the POSIX classes used inside
+// character sets ([[:alpha:]], [[:^digit:]]). The characters of a class are
+// converted once to a spanSet, which is then used for matching and generation,
+// as for any other character set.
+
+import (
+	"math/rand"
+	"slices"
+	"unicode"
+)
+
+type (
+	// class is a named class of characters.
+	class struct {
+		mod     *modifier
+		name    string
+		exclude bool
+		posix   bool
+
+		span spanSet
+	}
+)
+
+// posixClasses are the ASCII classes which can be used in character sets as [:name:].
+var posixClasses = map[string]spanSet{
+	"alnum":  {{'0', '9'}, {'A', 'Z'}, {'a', 'z'}},
+	"alpha":  {{'A', 'Z'}, {'a', 'z'}},
+	"ascii":  {{0, 0x7f}},
+	"blank":  {{'\t', '\t'}, {' ', ' '}},
+	"cntrl":  {{0, 0x1f}, {0x7f, 0x7f}},
+	"digit":  {{'0', '9'}},
+	"graph":  {{'!', '~'}},
+	"lower":  {{'a', 'z'}},
+	"print":  {{' ', '~'}},
+	"punct":  {{'!', '/'}, {':', '@'}, {'[', '`'}, {'{', '~'}},
+	"space":  {{'\t', '\r'}, {' ', ' '}},
+	"upper":  {{'A', 'Z'}},
+	"word":   {{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}},
+	"xdigit": {{'0', '9'}, {'A', 'F'}, {'a', 'f'}},
+}
+
+// newClass returns the class with the name, which is a POSIX class if posix is true, or
+// a Unicode category, script or property otherwise. It returns nil if there is no such class.
+func newClass(mod *modifier, name string, exclude, posix bool) *class {
+	var spans spanSet
+	if posix {
+		spans = posixClasses[name]
+	} else if name == "Any" {
+		spans = allUnicode
+	} else if table := unicodeTable(name); table != nil {
+		spans = tableSpans(table)
+	}
+	if spans == nil {
+		return nil
+	}
+	if mod.caseInsensitive {
+		spans = spans.fold()
+	}
+	if exclude {
+		spans = spans.invertUnicode()
+	}
+	return &class{mod, name, exclude, posix, spans}
+}
+
+// unicodeTable returns the table of the Unicode category, script or property with the name.
+func unicodeTable(name string) *unicode.RangeTable {
+	if t, ok := unicode.Categories[name]; ok {
+		return t
+	} else if t, ok := unicode.Scripts[name]; ok {
+		return t
+	} else if t, ok := unicode.Properties[name]; ok {
+		return t
+	}
+	return nil
+}
+
+// tableSpans returns the characters of a Unicode range table as a spanSet.
+func tableSpans(table *unicode.RangeTable) spanSet {
+	var spans spanSet
+	for _, r := range table.R16 {
+		spans = appendRange(spans, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		spans = appendRange(spans, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return spans.compact()
+}
+
+func appendRange(spans spanSet, lo, hi, stride rune) spanSet {
+	if stride == 1 {
+		return append(spans, span{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		spans = append(spans, span{r, r})
+	}
+	return spans
+}
+
+// fold returns the spans with all the case variants of their characters added.
+func (r spanSet) fold() spanSet {
+	var runes []rune
+	for _, s := range r {
+		for c := s.from; c <= s.to; c++ {
+			runes = append(runes, c)
+			for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+				runes = append(runes, f)
+			}
+		}
+	}
+	slices.Sort(runes)
+	var spans spanSet
+	for _, c := range slices.Compact(runes) {
+		if n := len(spans); n > 0 && spans[n-1].to == c-1 {
+			spans[n-1].to = c
+		} else {
+			spans = append(spans, span{c, c})
+		}
+	}
+	return spans
+}
+
+// String returns the class, in a character set if it is a POSIX class, and in a
+// case-insensitive group in case-insensitive mode.
+func (c *class) String() string {
+	s := c.content()
+	if c.posix {
+		s = "[" + s + "]"
+	}
+	if c.mod.caseInsensitive {
+		s = "(?i:" + s + ")"
+	}
+	return s
+}
+
+// content returns the class as written inside a character set.
+func (c *class) content() string {
+	if c.posix {
+		if c.exclude {
+			return "[:^" + c.name + ":]"
+		}
+		return "[:" + c.name + ":]"
+	}
+	if c.exclude {
+		return "\\P{" + c.name + "}"
+	}
+	return "\\p{" + c.name + "}"
+}
+
+func (c *class) isEmpty() bool {
+	return false
+}
+
+func (c *class) nfa() *automata {
+	return charNfa(c)
+}
+
+func (c *class) match(r rune) bool {
+	return c.span.match(r)
+}
+
+func (c *class) spanSet() spanSet {
+	return c.span
+}
+
+func (c *class) random(rng *rand.Rand) string {
+	return string(c.span.random(rng))
+}
+
+func (c *class) modifier() *modifier {
+	return c.mod
+}
@@ -0,0 +1,103 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestUnicodeClasses(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"\\p{L}+", "héllo", true},
+		{"\\p{L}+", "hé1lo", false},
+		{"\\pL\\pN", "é7", true},
+		{"\\p{Greek}+", "αβγ", true},
+		{"\\p{Greek}+", "abc", false},
+		{"\\P{Greek}+", "abc", true},
+		{"\\p{^Greek}", "α", false},
+		{"\\p{Nd}+", "42٤٢", true},
+		{"\\p{Lu}", "Ä", true},
+		{"\\p{Lu}", "ä", false},
+		{"(?i)\\p{Lu}", "ä", true},
+		{"\\p{White_Space}", " ", true},
+		{"\\p{Any}", "\U0001F600", true},
+		{"[\\p{L}_][\\p{L}\\p{Nd}_]*", "_переменная1", true},
+		{"[^\\p{L}]", "ж", false},
+	}
+	for _, test := range tests {
+		r, err := Compile(test.pattern)
+		if err != nil {
+			t.Error(err)
+		} else if r.Match(test.input) != test.match {
+			t.Errorf("%q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+	}
+}
+
+func TestPosixClasses(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"[[:alpha:]]+", "abcXYZ", true},
+		{"[[:alpha:]]+", "ab1", false},
+		{"[[:alpha:][:digit:]_]+", "ab_12", true},
+		{"[[:^digit:]]+", "abc", true},
+		{"[[:^digit:]]+", "a1", false},
+		{"[^[:space:]]+", "a b", false},
+		{"[[:xdigit:]]{4}", "0aF9", true},
+		{"[[:punct:]]", "!", true},
+		{"[[:upper:]]", "a", false},
+		{"(?i)[[:upper:]]", "a", true},
+	}
+	for _, test := range tests {
+		r, err := Compile(test.pattern)
+		if err != nil {
+			t.Error(err)
+		} else if r.Match(test.input) != test.match {
+			t.Errorf("%q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+	}
+}
+
+func TestClassErrors(t *testing.T) {
+	for _, pattern := range []string{"\\p{Klingon}", "\\p{L", "[[:alphabet:]]", "[[:alpha]"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("%q compiled without error", pattern)
+		}
+		if _, err := Compile(pattern, Lenient()); err != nil {
+			t.Errorf("%q not compiled leniently: %v", pattern, err)
+		}
+	}
+}
+
+func TestGenerateClass(t *testing.T) {
+	r := MustCompile("\\p{Greek}{10}")
+	for i := 0; i < 10; i++ {
+		s := r.Generate()
+		if utf8.RuneCountInString(s) != 10 {
+			t.Errorf("expected 10 characters, got %q", s)
+		}
+		for _, c := range s {
+			if !unicode.Is(unicode.Greek, c) {
+				t.Errorf("%q is not Greek in %q", c, s)
+			}
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	// k folds to K and to the Kelvin sign
+	spans := spanSet{{'a', 'c'}, {'k', 'k'}}.fold()
+	expected := spanSet{{'A', 'C'}, {'K', 'K'}, {'a', 'c'}, {'k', 'k'}, {'\u212a', '\u212a'}}
+	if spans.String() != expected.String() {
+		t.Error("expected", expected, "actual", spans)
+	}
+}
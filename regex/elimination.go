@@ -0,0 +1,141 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// A DFA is converted back to a regular expression by state elimination. The DFA
+// is first extended with a new start state and a new final state, linked to the
+// old ones by empty transitions, and its transitions are labelled with patterns
+// instead of characters. Every other state q is then eliminated in turn, replacing
+// each path p -> q -> r by a transition from p to r labelled with the pattern of
+// the path, R1 (R2)* R3 where R2 is the label of the loop on q, if any, and combined
+// with the existing transition from p to r in a choice. When only the new start
+// and final states are left, the label of the transition between them is the
+// regular expression of the DFA.
+//
+// States with the fewest paths through them are eliminated first, which keeps the
+// patterns small, and patterns are simplified as they are combined and at the end. States and
+// transitions are visited in order of their characters, so that the same DFA always
+// gives the same regular expression.
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// Pattern returns a regular expression matching the strings accepted by the automata,
+// which can be a DFA from a compiled regular expression or from the combination of
+// regular expressions. The regular expression is built by state elimination from the
+// start state with the final states at the end of the input; the contexts of other
+// start states and of other next characters are not kept.
+func (auto *automata) Pattern() Pattern {
+	// number the states breadth-first, from 1, with 0 for the new start state and
+	// the number after the last state for the new final state
+	ids := map[state]int{auto.start: 1}
+	order := []state{auto.start}
+	for i := 0; i < len(order); i++ {
+		for _, t := range auto.sortedTransitions(order[i]) {
+			if _, ok := ids[t.to]; !ok {
+				order = append(order, t.to)
+				ids[t.to] = len(order)
+			}
+		}
+	}
+	start, final := 0, len(order)+1
+
+	out := map[int]map[int]Pattern{}
+	in := map[int]set[int]{}
+	link := func(from, to int, p Pattern) {
+		if out[from] == nil {
+			out[from] = map[int]Pattern{}
+		}
+		if in[to] == nil {
+			in[to] = set[int]{}
+		}
+		out[from][to] = alt(out[from][to], p)
+		in[to][from] = true
+	}
+	link(start, 1, &sequence{})
+	for _, s := range order {
+		for _, t := range auto.sortedTransitions(s) {
+			link(ids[s], ids[t.to], label(t.char))
+		}
+		if auto.finalMap[s] {
+			link(ids[s], final, &sequence{})
+		}
+	}
+
+	remaining := set[int]{}
+	for i := 1; i < final; i++ {
+		remaining[i] = true
+	}
+	for len(remaining) > 0 {
+		// eliminate the state with the fewest paths through it
+		q, paths := 0, 0
+		for s := range remaining {
+			n := len(in[s]) * len(out[s])
+			if q == 0 || n < paths || (n == paths && s < q) {
+				q, paths = s, n
+			}
+		}
+		delete(remaining, q)
+
+		loop := star(out[q][q])
+		sources := slices.Sorted(maps.Keys(in[q]))
+		targets := slices.Sorted(maps.Keys(out[q]))
+		for _, p := range sources {
+			if p != q {
+				for _, r := range targets {
+					if r != q {
+						link(p, r, cat(out[p][q], loop, out[q][r]))
+					}
+				}
+			}
+		}
+		for _, p := range sources {
+			delete(out[p], q)
+		}
+		for _, r := range targets {
+			delete(in[r], q)
+		}
+		delete(out, q)
+		delete(in, q)
+	}
+
+	if p, ok := out[start][final]; ok {
+		return Simplify(p)
+	}
+	// no string is accepted
+	return &charSet{mod: &modifier{}, span: spanSet{}}
+}
+
+// sortedTransitions returns the transitions leaving the state in order of their
+// characters, as given by the representative character of their spans.
+func (auto *automata) sortedTransitions(s state) []transition {
+	var trans []transition
+	for c, t := range auto.Trans[s] {
+		trans = append(trans, transition{c, t})
+	}
+	key := func(c char) rune {
+		if spans := c.spanSet(); len(spans) > 0 {
+			return spans.representative()
+		}
+		return -1
+	}
+	slices.SortFunc(trans, func(a, b transition) int {
+		if k := cmp.Compare(key(a.char), key(b.char)); k != 0 {
+			return k
+		}
+		return cmp.Compare(a.char.String(), b.char.String())
+	})
+	return trans
+}
+
+// label returns the pattern of a transition of the DFA: a character set of the
+// characters of the transition, or the character itself if it has no spans (lists).
+func label(c char) Pattern {
+	if spans := c.spanSet(); spans != nil {
+		return &charSet{mod: &modifier{}, span: slices.Clone(spans).compact()}
+	}
+	return c
+}
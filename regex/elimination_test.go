@@ -0,0 +1,112 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestEliminationRoundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"a",
+		"a?",
+		"a|b",
+		"(ab)*c",
+		"[a-z]+",
+		"x[0-9]*y",
+		"a{2,4}",
+		"(a|b)*abb",
+		"[^a]",
+		".",
+		"(?s).",
+		"(?i)ab",
+		"\\d+\\.\\d*",
+		"[\\]\\-^\\\\]+",
+		"\\n\\t\\x00\\x{10ffff}",
+		"[()|*+?{}$.#]",
+		"\\bab\\b",
+		"(0|1(01*0)*1)*",
+		"if|else|for|while",
+	}
+	for _, test := range tests {
+		r := NewRegex(test)
+		p := r.Dfa.Pattern().String()
+		back, err := Compile(p)
+		if err != nil {
+			t.Errorf("%q: pattern %q does not compile: %v", test, p, err)
+			continue
+		}
+		if equivalent, s := Equivalent(r, back); !equivalent {
+			t.Errorf("%q: pattern %q differs on %q", test, p, s)
+		}
+	}
+}
+
+func TestEliminationMembership(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		matched  []string
+		rejected []string
+	}{
+		{"ab*|", []string{"", "a", "abbb"}, []string{"b", "bb", "ba"}},
+		{"(?:ab*)?", []string{"", "a", "ab"}, []string{"b", "aab"}},
+		{"(?:ab*)*", []string{"", "a", "abab", "aab"}, []string{"b", "ba"}},
+		{"x(?:ab*)?y", []string{"xy", "xay", "xabby"}, []string{"xby", "xaby y"}},
+		{"(?:a+b)*", []string{"", "ab", "aabab"}, []string{"a", "b", "abb"}},
+	}
+	for _, test := range tests {
+		r := NewRegex(test.pattern)
+		p := r.Dfa.Pattern().String()
+		for _, re := range []*Regex{r, NewRegex(p)} {
+			for _, s := range test.matched {
+				if !re.Match(s) {
+					t.Errorf("%q (from %q): %q not matched", re.Pattern, test.pattern, s)
+				}
+			}
+			for _, s := range test.rejected {
+				if re.Match(s) {
+					t.Errorf("%q (from %q): %q matched", re.Pattern, test.pattern, s)
+				}
+			}
+		}
+	}
+}
+
+func TestEliminationPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"a|b", "[ab]"},
+		{"[a-z]+", "[a-z]+"},
+		{"a?", "a?"},
+		{"ab|ac", "a[bc]"},
+		{"(ab)*", "(?:ab)*"},
+		{"[^a]", "[^a]"},
+		{"\\.", "\\."},
+		{"(?i)k", "[Kk]"},
+	}
+	for _, test := range tests {
+		p := NewRegex(test.pattern).Dfa.Pattern().String()
+		if p != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.pattern, test.expected, p)
+		}
+	}
+}
+
+func TestEliminationCombination(t *testing.T) {
+	keywords := NewRegex("if|else|for")
+	notKeyword := keywords.Complement()
+	back, err := Compile(notKeyword.String())
+	if err != nil {
+		t.Fatalf("%q does not compile: %v", notKeyword.String(), err)
+	}
+	if equivalent, s := Equivalent(notKeyword, back); !equivalent {
+		t.Errorf("%q differs from the complement of %q on %q", notKeyword.String(), keywords, s)
+	}
+	none := NewRegex("[a-z]+").Intersect(NewRegex("[0-9]+"))
+	if back := NewRegex(none.String()); !back.MatchNone() {
+		t.Errorf("%q matches some strings", none.String())
+	}
+}
@@ -0,0 +1,96 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// The strings matched by a regular expression are enumerated from the same disjoint
+// DFA and counts of paths as for uniform generation (see uniform.go): for each length
+// in turn, the DFA is explored depth-first from the start state, following the
+// characters of the transitions in increasing order and only the transitions leading
+// to a state accepting strings of the remaining length.
+
+import (
+	"cmp"
+	"iter"
+	"math/big"
+	"slices"
+)
+
+// Enumerate returns the strings matched by the regular expression with at most maxLen
+// characters, in shortlex order: by length and, for the same length, in the order of
+// their characters. Surrogate halves, which are not valid characters, are skipped, as
+// they are by Count.
+func (r *Regex) Enumerate(maxLen int) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		g := r.Uniform(0, maxLen)
+		for n := 0; n <= maxLen; n++ {
+			if !g.enumerate(g.dfa.start, n, nil, yield) {
+				return
+			}
+		}
+	}
+}
+
+// Count returns the number of strings of n characters matched by the regular expression.
+func (r *Regex) Count(n int) *big.Int {
+	return r.Uniform(n, n).Count()
+}
+
+// Finite returns true if the regular expression matches a finite number of strings,
+// which is when no cycle of its DFA can lead to a final state.
+func (r *Regex) Finite() bool {
+	dfa := r.automaton().disjoint().trim()
+
+	// depth-first search for a state reached again while its successors are explored
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	status := map[state]int{}
+	var cyclic func(s state) bool
+	cyclic = func(s state) bool {
+		status[s] = visiting
+		for _, t := range dfa.Trans[s] {
+			if status[t] == visiting || (status[t] == unvisited && cyclic(t)) {
+				return true
+			}
+		}
+		status[s] = visited
+		return false
+	}
+	return !cyclic(dfa.start)
+}
+
+// enumerate calls yield with the prefix followed by each string of n characters accepted
+// from the state, in order, and returns false as soon as yield does.
+func (g *UniformGenerator) enumerate(s state, n int, prefix []rune, yield func(string) bool) bool {
+	if _, ok := g.counts[n][s]; !ok {
+		return true
+	}
+	if n == 0 {
+		return yield(string(prefix))
+	}
+	type step struct {
+		chars  span
+		target state
+	}
+	var steps []step
+	for c, t := range g.dfa.Trans[s] {
+		if _, ok := g.counts[n-1][t]; ok {
+			for _, chars := range g.chars[c] {
+				steps = append(steps, step{chars, t})
+			}
+		}
+	}
+	slices.SortFunc(steps, func(a, b step) int {
+		return cmp.Compare(a.chars.from, b.chars.from)
+	})
+	for _, st := range steps {
+		for c := st.chars.from; c <= st.chars.to; c++ {
+			if !g.enumerate(st.target, n-1, append(prefix, c), yield) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math/big"
+	"slices"
+	"testing"
+)
+
+func TestEnumerate(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		maxLen   int
+		expected []string
+	}{
+		{"if|else|for|[xy]", 10, []string{"x", "y", "if", "for", "else"}},
+		{"[ab]*", 2, []string{"", "a", "b", "aa", "ab", "ba", "bb"}},
+		{"b|ab|a", 5, []string{"a", "b", "ab"}},
+		{"x(ab)*", 5, []string{"x", "xab", "xabab"}},
+		{"(?i)ok", 2, []string{"OK", "Ok", "oK", "ok"}},
+		{"\\bab\\b", 3, []string{"ab"}},
+		{"[0-9]{3}", 2, nil},
+		{"[a-z]+-if", 3, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			got := slices.Collect(NewRegex(test.pattern).Enumerate(test.maxLen))
+			if !slices.Equal(got, test.expected) {
+				t.Errorf("Enumerate(%d) = %q, expected %q", test.maxLen, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestEnumerateStop(t *testing.T) {
+	var got []string
+	for s := range NewRegex("[a-z]*").Enumerate(100) {
+		got = append(got, s)
+		if len(got) == 4 {
+			break
+		}
+	}
+	if expected := []string{"", "a", "b", "c"}; !slices.Equal(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestEnumerateMinus(t *testing.T) {
+	r := NewRegex("[a-z]{2}").Minus(NewRegex("if|[b-z][a-z]"))
+	got := slices.Collect(r.Enumerate(2))
+	if len(got) != 26 || got[0] != "aa" || got[25] != "az" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		n        int
+		expected *big.Int
+	}{
+		{"if|else|for", 2, big.NewInt(1)},
+		{"[a-z]+", 3, big.NewInt(26 * 26 * 26)},
+		{"[A-Z]{2}[0-9]{4}", 6, big.NewInt(26 * 26 * 10000)},
+		{"[A-Z]{2}[0-9]{4}", 5, big.NewInt(0)},
+		{"[0-9]*", 30, new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)},
+		{".", 1, big.NewInt(0x110000 - 0x800 - 1)}, // all characters but surrogates and \n
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			if got := NewRegex(test.pattern).Count(test.n); got.Cmp(test.expected) != 0 {
+				t.Errorf("Count(%d) = %v, expected %v", test.n, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestCountEnumerated(t *testing.T) {
+	for _, pattern := range []string{".", "[^a]", "[\\x{D7F0}-\\x{E010}]x?"} {
+		r := NewRegex(pattern)
+		enumerated := 0
+		for range r.Enumerate(1) {
+			enumerated++
+		}
+		if count := r.Count(0).Int64() + r.Count(1).Int64(); count != int64(enumerated) {
+			t.Errorf("%q: Count = %d, but %d strings enumerated", pattern, count, enumerated)
+		}
+	}
+}
+
+func TestFinite(t *testing.T) {
+	tests := []struct {
+		pattern string
+		finite  bool
+	}{
+		{"if|else|for", true},
+		{"[a-z]{1,5}", true},
+		{"a*", false},
+		{"x(ab)+y", false},
+		{"[0-9]{3}|a*b", false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			if got := NewRegex(test.pattern).Finite(); got != test.finite {
+				t.Errorf("Finite() = %v, expected %v", got, test.finite)
+			}
+		})
+	}
+	if !NewRegex("a[0-9]*").Intersect(NewRegex("a[0-5]")).Finite() {
+		t.Errorf("intersection of a[0-9]* and a[0-5] is not finite")
+	}
+}
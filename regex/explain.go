@@ -0,0 +1,330 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// A regular expression is explained by walking its pattern and describing each node
+// in English. The description of a node is a list of lines: the first one describes
+// the node and the following ones, indented, describe its operands, unless a single
+// line is enough for both. Consecutive characters are described together as a text.
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// indent is added before the description of the operands of a node.
+const indent = "  "
+
+// Explain returns a description in English of the regular expression, indented to
+// show its structure. For example, \d+(-\d{2})? is described as:
+//
+//	one or more of: a digit 0–9
+//	followed by optionally:
+//	  group 1 containing:
+//	    the character '-'
+//	    followed by exactly 2 of: a digit 0–9
+func (r *Regex) Explain() string {
+	return strings.Join(explain(r.Pattern), "\n")
+}
+
+// explain returns the lines describing the pattern.
+func explain(p Pattern) []string {
+	switch p := p.(type) {
+	case *choice:
+		var lines []string
+		for i, a := range alternatives(p) {
+			if i == 0 {
+				lines = append(lines, inline("either", " ", explain(a))...)
+			} else {
+				lines = append(lines, inline("or", " ", explain(a))...)
+			}
+		}
+		return lines
+	case *sequence:
+		if len(p.sequence) == 0 {
+			return []string{"the empty string"}
+		}
+		var lines []string
+		for i, e := range texts(p.sequence) {
+			l := explain(e)
+			if _, ok := e.(*choice); ok {
+				// alternatives are indented to separate them from the rest of the sequence
+				l = block("one of:", l)
+			}
+			if i > 0 {
+				l[0] = "followed by " + l[0]
+			}
+			lines = append(lines, l...)
+		}
+		return lines
+	case *zeroOrOne:
+		return inline("optionally", " ", explain(p.opt))
+	case *zeroOrMore:
+		return inline("zero or more of", ": ", explain(p.re))
+	case *oneOrMore:
+		return inline("one or more of", ": ", explain(p.re))
+	case *repeat:
+		var head string
+		switch {
+		case p.unbounded:
+			head = "at least " + strconv.Itoa(p.min) + " of"
+		case p.min == p.max:
+			head = "exactly " + strconv.Itoa(p.min) + " of"
+		default:
+			head = "between " + strconv.Itoa(p.min) + " and " + strconv.Itoa(p.max) + " of"
+		}
+		return inline(head, ": ", explain(p.re))
+	case *captureGroup:
+		head := "group " + strconv.Itoa(p.group)
+		if p.name != "" {
+			head += " named " + p.name
+		}
+		return block(head+" containing:", explain(p.re))
+	case *combination:
+		var lines []string
+		switch p.op {
+		case "&":
+			lines = append(lines, block("the strings matched by both:", explain(p.operands[0]))...)
+			lines = append(lines, block("and:", explain(p.operands[1]))...)
+		case "-":
+			lines = append(lines, block("the strings matched by:", explain(p.operands[0]))...)
+			lines = append(lines, block("but not by:", explain(p.operands[1]))...)
+		default:
+			lines = append(lines, block("the strings not matched by:", explain(p.operands[0]))...)
+		}
+		return lines
+	case *text:
+		if p.caseInsensitive {
+			return []string{"the text " + strconv.Quote(p.text) + " in any case"}
+		}
+		return []string{"the text " + strconv.Quote(p.text)}
+	case *assertion:
+		return []string{explainAssertion(p)}
+	case *inList:
+		return []string{explainList(p)}
+	case *anyChar:
+		if p.mod.dotAll {
+			return []string{"any character"}
+		}
+		return []string{"any character except newline"}
+	case *singleChar:
+		s := "the character " + strconv.QuoteRune(p.char)
+		if len(p.spanSet()) > 1 {
+			s += " in any case"
+		}
+		return []string{s}
+	case *class:
+		return []string{explainClass(p)}
+	case *charSet:
+		if !p.plain() {
+			var items []string
+			for cs := p.sets.Front(); cs != nil; cs = cs.Next() {
+				items = append(items, explainItem(cs.Value.(char)))
+			}
+			if p.exclude {
+				return []string{"any character except " + enumerate(items, "or")}
+			}
+			return []string{"a character in " + enumerate(items, "or")}
+		}
+	}
+	if c, ok := p.(char); ok {
+		return []string{explainSpans(c.spanSet())}
+	}
+	return []string{p.String()}
+}
+
+// text is a sequence of characters described together.
+type text struct {
+	sequence
+	text            string
+	caseInsensitive bool
+}
+
+// texts returns the patterns of a sequence with consecutive single characters with the
+// same case sensitivity combined into texts.
+func texts(seq []Pattern) []Pattern {
+	var result []Pattern
+	for _, p := range seq {
+		c, ok := p.(*singleChar)
+		if ok {
+			insensitive := len(c.spanSet()) > 1
+			if n := len(result); n > 0 {
+				if t, ok := result[n-1].(*text); ok && t.caseInsensitive == insensitive {
+					t.text += string(c.char)
+					continue
+				} else if last, ok := result[n-1].(*singleChar); ok && (len(last.spanSet()) > 1) == insensitive {
+					result[n-1] = &text{text: string(last.char) + string(c.char), caseInsensitive: insensitive}
+					continue
+				}
+			}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// inline returns the description of a node with a single operand, on the same line as
+// the description of the operand if it has a single line, joined with sep.
+func inline(head, sep string, operand []string) []string {
+	if len(operand) == 1 {
+		return []string{head + sep + operand[0]}
+	}
+	return block(head+":", operand)
+}
+
+// block returns the description of a node followed by the description of its operand, indented.
+func block(head string, operand []string) []string {
+	lines := []string{head}
+	for _, l := range operand {
+		lines = append(lines, indent+l)
+	}
+	return lines
+}
+
+func explainAssertion(a *assertion) string {
+	switch a.kind {
+	case TextStart:
+		if a.mod.multiline {
+			return "the start of a line"
+		}
+		return "the start of the text"
+	case TextEnd:
+		if a.mod.multiline {
+			return "the end of a line"
+		}
+		return "the end of the text"
+	case WordBoundary:
+		return "a word boundary"
+	default:
+		return "a position which is not a word boundary"
+	}
+}
+
+func explainList(l *inList) string {
+	s := "a word from the list " + l.list
+	var conversions []string
+	if l.convert.lower {
+		conversions = append(conversions, "in lower case")
+	}
+	if l.convert.upper {
+		conversions = append(conversions, "in upper case")
+	}
+	if l.convert.title {
+		conversions = append(conversions, "in title case")
+	}
+	if l.convert.trim {
+		conversions = append(conversions, "trimmed")
+	}
+	if l.convert.singleSpace {
+		conversions = append(conversions, "with single spaces")
+	}
+	if len(conversions) > 0 {
+		s += ", " + enumerate(conversions, "and")
+	}
+	return s
+}
+
+func explainClass(c *class) string {
+	kind := "Unicode class"
+	if c.posix {
+		kind = "POSIX class"
+	}
+	s := "a character of the " + kind + " " + c.name
+	if c.exclude {
+		s = "a character not of the " + kind + " " + c.name
+	}
+	if c.mod.caseInsensitive {
+		s += " in any case"
+	}
+	return s
+}
+
+// explainItem describes a character in a set.
+func explainItem(c char) string {
+	if cl, ok := c.(*class); ok {
+		if cl.exclude {
+			return "not the " + strings.TrimPrefix(explainClass(cl), "a character not of the ")
+		}
+		return "the " + strings.TrimPrefix(explainClass(cl), "a character of the ")
+	}
+	return ranges(c.spanSet())
+}
+
+var (
+	digitSpans = spanSet{{'0', '9'}}
+	spaceSpans = spanSet{{'\t', '\n'}, {'\f', '\r'}, {' ', ' '}}
+)
+
+// explainSpans describes a character matching the spans.
+func explainSpans(spans spanSet) string {
+	spans = slices.Clone(spans).compact()
+	switch {
+	case slices.Equal(spans, digitSpans):
+		return "a digit 0–9"
+	case slices.Equal(spans, wordSpans):
+		return "a word character (a–z, A–Z, 0–9 or _)"
+	case slices.Equal(spans, spaceSpans):
+		return "a whitespace character"
+	case len(spans) == 0:
+		return "no character"
+	}
+	if inverted := spans.invertUnicode(); spans[len(spans)-1].to == unicode.MaxRune && len(inverted) > 0 {
+		switch {
+		case slices.Equal(inverted, digitSpans):
+			return "a character which is not a digit"
+		case slices.Equal(inverted, wordSpans):
+			return "a character which is not a word character"
+		case slices.Equal(inverted, spaceSpans):
+			return "a character which is not whitespace"
+		}
+		return "any character except " + ranges(inverted)
+	}
+	if len(spans) == 1 && spans[0].from == spans[0].to {
+		return "the character " + strconv.QuoteRune(spans[0].from)
+	}
+	return "a character in " + ranges(spans)
+}
+
+// ranges describes the ranges of the spans, such as a–z, 0–9 or _.
+func ranges(spans spanSet) string {
+	var items []string
+	for _, s := range slices.Clone(spans).compact() {
+		switch {
+		case s.from == s.to:
+			items = append(items, charName(s.from))
+		case s.to == s.from+1:
+			items = append(items, charName(s.from), charName(s.to))
+		default:
+			items = append(items, charName(s.from)+"–"+charName(s.to))
+		}
+	}
+	return enumerate(items, "or")
+}
+
+// charName returns the character if it is visible, or its name or code point otherwise.
+func charName(c rune) string {
+	switch {
+	case c == ' ':
+		return "space"
+	case c == '\n':
+		return "newline"
+	case c == '\t':
+		return "tab"
+	case c == '\r':
+		return "carriage return"
+	case unicode.IsGraphic(c):
+		return string(c)
+	}
+	return fmt.Sprintf("U+%04X", c)
+}
+
+// enumerate joins the items with commas, and the conjunction before the last one.
+func enumerate(items []string, conjunction string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
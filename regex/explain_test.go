@@ -0,0 +1,66 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{`\d+(-\d{2})?`, "one or more of: a digit 0–9\n" +
+			"followed by optionally:\n" +
+			"  group 1 containing:\n" +
+			"    the character '-'\n" +
+			"    followed by exactly 2 of: a digit 0–9"},
+		{`(?i)select\s+(?<col>\w+)`, "the text \"select\" in any case\n" +
+			"followed by one or more of: a whitespace character\n" +
+			"followed by group 1 named col containing:\n" +
+			"  one or more of: a word character (a–z, A–Z, 0–9 or _)"},
+		{`^(?m:^)a.(?s:.)$`, "the start of the text\n" +
+			"followed by the start of a line\n" +
+			"followed by the character 'a'\n" +
+			"followed by any character except newline\n" +
+			"followed by any character\n" +
+			"followed by the end of the text"},
+		{`if|else|[^a-z]`, "either the text \"if\"\n" +
+			"or the text \"else\"\n" +
+			"or any character except a–z"},
+		{`a(?:b|c)d`, "the character 'a'\n" +
+			"followed by one of:\n" +
+			"  either the character 'b'\n" +
+			"  or the character 'c'\n" +
+			"followed by the character 'd'"},
+		{`[\p{Greek}0-9]+\b`, "one or more of: a character in the Unicode class Greek or 0–9\n" +
+			"followed by a word boundary"},
+		{`(:word_en:ut){1,3}`, "between 1 and 3 of: a word from the list word_en, in upper case and in title case"},
+		{`[^\d]\W\S`, "a character which is not a digit\n" +
+			"followed by a character which is not a word character\n" +
+			"followed by a character which is not whitespace"},
+		{`x{2,}y*[ \n]`, "at least 2 of: the character 'x'\n" +
+			"followed by zero or more of: the character 'y'\n" +
+			"followed by a character in newline or space"},
+		{``, "the empty string"},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			if got := NewRegex(test.pattern).Explain(); got != test.expected {
+				t.Errorf("Explain(%q) = \n%s\nexpected\n%s", test.pattern, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestExplainCombination(t *testing.T) {
+	r := NewRegex("[a-z]+").Minus(NewRegex("if"))
+	expected := "the strings matched by:\n" +
+		"  one or more of: a character in a–z\n" +
+		"but not by:\n" +
+		"  the text \"if\""
+	if got := r.Explain(); got != expected {
+		t.Errorf("got \n%s\nexpected\n%s", got, expected)
+	}
+}
@@ -0,0 +1,231 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"strings"
+)
+
+type (
+	// Found is a match of a regular expression found in a text by a search.
+	Found struct {
+		// Start is the byte offset of the start of the match in the input.
+		Start int
+
+		// End is the byte offset just after the end of the match in the input.
+		End int
+
+		// Text is the text of the match.
+		Text string
+
+		// Groups are the groups captured by the capturing groups of the regular
+		// expression in this match, with group 0 being the whole match. Positions
+		// of groups are byte offsets in the input.
+		Groups []Group
+	}
+
+	// runeBuffer holds the characters read from a reader which are still needed by
+	// a search, together with their byte offsets in the input.
+	runeBuffer struct {
+		in      io.RuneReader
+		runes   []rune
+		offsets []int // offsets[i] is the byte offset of runes[i]; one more offset is kept for the end
+		first   int   // index in the input of runes[0]
+		eof     bool
+		err     error
+	}
+)
+
+// Find returns the leftmost-longest match of the regular expression in the input,
+// or nil if there is none.
+func (r *Regex) Find(input string) *Found {
+	return r.Matcher().FindNext(input)
+}
+
+// FindIndex returns the start and end byte offsets of the leftmost-longest match
+// of the regular expression in the input, or nil if there is none.
+func (r *Regex) FindIndex(input string) []int {
+	if f := r.Find(input); f != nil {
+		return []int{f.Start, f.End}
+	}
+	return nil
+}
+
+// FindAll returns an iterator over all successive non-overlapping leftmost-longest
+// matches of the regular expression in the input. An empty match immediately after
+// a previous match is ignored.
+func (r *Regex) FindAll(input string) iter.Seq[*Found] {
+	return func(yield func(*Found) bool) {
+		for f := range r.Matcher().find(strings.NewReader(input)) {
+			if !yield(f) {
+				return
+			}
+		}
+	}
+}
+
+// FindAllIndex returns an iterator over the start and end byte offsets of all
+// successive non-overlapping matches of the regular expression in the input.
+func (r *Regex) FindAllIndex(input string) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		for f := range r.FindAll(input) {
+			if !yield([]int{f.Start, f.End}) {
+				return
+			}
+		}
+	}
+}
+
+// FindReader returns the leftmost-longest match of the regular expression in
+// the text read from in, or nil if there is none. Only the text needed to find
+// the match is read.
+func (r *Regex) FindReader(in io.Reader) (*Found, error) {
+	for f, err := range r.FindAllReader(in) {
+		return f, err
+	}
+	return nil, nil
+}
+
+// FindAllReader returns an iterator over all successive non-overlapping matches
+// of the regular expression in the text read from in. Text is read as required
+// and discarded once it cannot be part of a match anymore. An error while reading
+// is returned in the last pair of the sequence.
+func (r *Regex) FindAllReader(in io.Reader) iter.Seq2[*Found, error] {
+	rr, ok := in.(io.RuneReader)
+	if !ok {
+		rr = bufio.NewReader(in)
+	}
+	return r.Matcher().find(rr)
+}
+
+// FindNext searches the input for the leftmost-longest match of the regular
+// expression, returning nil if there is none. The matcher is reset and used for
+// the search.
+func (m *Matcher) FindNext(input string) *Found {
+	for f := range m.find(strings.NewReader(input)) {
+		return f
+	}
+	return nil
+}
+
+// find searches for successive non-overlapping matches by trying to match at every
+// position in the input, starting with the leftmost one, and keeping the longest
+// full match at that position.
+func (m *Matcher) find(in io.RuneReader) iter.Seq2[*Found, error] {
+	return func(yield func(*Found, error) bool) {
+		buffer := &runeBuffer{in: in, offsets: []int{0}}
+		start := 0
+		lastEnd := -1
+		for buffer.has(start - 1) {
+			if start == 0 {
+				m.Reset()
+			} else {
+				m.ResetAfter(buffer.at(start - 1))
+			}
+
+			end := -1
+			var captures []int
+			if start != lastEnd && m.fullMatchAt(buffer, start) {
+				end = start
+				captures = m.capturesAt(buffer, start)
+			}
+			for i := start; buffer.has(i); i++ {
+				if m.MatchNext(buffer.at(i)) == NoMatch {
+					break
+				} else if m.fullMatchAt(buffer, i+1) {
+					end = i + 1
+					captures = m.capturesAt(buffer, i+1)
+				}
+			}
+			if buffer.err != nil {
+				yield(nil, buffer.err)
+				return
+			}
+
+			if end == -1 {
+				start++
+			} else {
+				f := &Found{
+					Start: buffer.offset(start),
+					End:   buffer.offset(end),
+					Text:  buffer.text(start, end),
+				}
+				f.Groups = groupsOf(f.Text, f.Start, captures)
+				if !yield(f, nil) {
+					return
+				}
+				lastEnd = end
+				if end == start {
+					start++
+				} else {
+					start = end
+				}
+			}
+			buffer.discard(start - 1)
+		}
+	}
+}
+
+// fullMatchAt returns true if the text matched so far is a full match when it
+// ends at index i of the input, taking the following character into account.
+func (m *Matcher) fullMatchAt(buffer *runeBuffer, i int) bool {
+	if buffer.has(i) {
+		return m.FullMatchBefore(buffer.at(i))
+	}
+	return m.LastMatch != NoMatch && m.final(atEdge)
+}
+
+// capturesAt returns the positions of the groups of the text matched so far, relative
+// to the start of the match, when the match ends at index i of the input.
+func (m *Matcher) capturesAt(buffer *runeBuffer, i int) []int {
+	if buffer.has(i) {
+		return m.capturesBefore(contextOf(buffer.at(i)))
+	}
+	return m.capturesBefore(atEdge)
+}
+
+// has returns true if the character at index i of the input exists, reading it if
+// necessary. Characters before the start of the buffer (including negative indices,
+// which simplifies the checks for the previous character) are considered to exist.
+func (b *runeBuffer) has(i int) bool {
+	for !b.eof && i-b.first >= len(b.runes) {
+		r, size, err := b.in.ReadRune()
+		if err != nil {
+			b.eof = true
+			if err != io.EOF {
+				b.err = err
+			}
+			break
+		}
+		b.runes = append(b.runes, r)
+		b.offsets = append(b.offsets, b.offsets[len(b.offsets)-1]+size)
+	}
+	return i < b.first || i-b.first < len(b.runes)
+}
+
+// at returns the character at index i in the input.
+func (b *runeBuffer) at(i int) rune {
+	return b.runes[i-b.first]
+}
+
+// offset returns the byte offset of the character at index i in the input.
+func (b *runeBuffer) offset(i int) int {
+	return b.offsets[i-b.first]
+}
+
+// text returns the text of the characters from index start (inclusive) to end (exclusive).
+func (b *runeBuffer) text(start, end int) string {
+	return string(b.runes[start-b.first : end-b.first])
+}
+
+// discard removes the characters before index i from the buffer.
+func (b *runeBuffer) discard(i int) {
+	if n := i - b.first; n > 0 && n <= len(b.runes) {
+		b.runes = b.runes[n:]
+		b.offsets = b.offsets[n:]
+		b.first = i
+	}
+}
@@ -0,0 +1,121 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	r := NewRegex("\\d+")
+	f := r.Find("abc 123 4567")
+	if f == nil || f.Text != "123" || f.Start != 4 || f.End != 7 {
+		t.Error("'\\d+' did not find '123' at [4, 7] in 'abc 123 4567':", f)
+	}
+	if r.Find("abc") != nil {
+		t.Error("'\\d+' found a match in 'abc'")
+	}
+	if !slices.Equal(r.FindIndex("日本 42"), []int{7, 9}) {
+		t.Error("'\\d+' did not find [7, 9] in '日本 42':", r.FindIndex("日本 42"))
+	}
+}
+
+func TestFindLongest(t *testing.T) {
+	r := NewRegex("ab|abcd|abc")
+	f := r.Find("xxabcde")
+	if f == nil || f.Text != "abcd" {
+		t.Error("'ab|abcd|abc' did not find 'abcd' in 'xxabcde':", f)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		input    string
+		expected []string
+	}{
+		{"\\d+", "a1 22 333b", []string{"1", "22", "333"}},
+		{"a*", "baaac", []string{"", "aaa", ""}},
+		{"\\bcat\\b", "cat concat cats cat", []string{"cat", "cat"}},
+		{"^\\w+", "first second", []string{"first"}},
+		{"\\w+$", "first second", []string{"second"}},
+		{"x", "", nil},
+		{"", "ab", []string{"", "", ""}},
+	}
+	for _, test := range tests {
+		var result []string
+		for f := range NewRegex(test.pattern).FindAll(test.input) {
+			result = append(result, f.Text)
+		}
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("%q in %q: expected %q, got %q", test.pattern, test.input, test.expected, result)
+		}
+	}
+}
+
+func TestFindAllIndex(t *testing.T) {
+	var result [][]int
+	for i := range NewRegex("[a-z]+").FindAllIndex("12 ab 345 cde") {
+		result = append(result, i)
+	}
+	expected := [][]int{{3, 5}, {10, 13}}
+	if !slices.EqualFunc(result, expected, slices.Equal) {
+		t.Error("expected", expected, "actual", result)
+	}
+}
+
+func TestFindGroups(t *testing.T) {
+	f := NewRegex("(\\d+)-(\\d+)").Find("tel: 230-5551234.")
+	if f == nil || f.Groups[0].Text != "230-5551234" || f.Groups[1].Text != "230" || f.Groups[2].Text != "5551234" ||
+		f.Groups[1].Start != 5 || f.Groups[2].End != 16 {
+		t.Error("'(\\d+)-(\\d+)' did not capture the groups of '230-5551234':", f)
+	}
+}
+
+func TestFindReader(t *testing.T) {
+	r := NewRegex("ERROR: \\w+")
+	var log strings.Builder
+	for i := 0; i < 1000; i++ {
+		log.WriteString("INFO: all good\n")
+		if i%100 == 0 {
+			log.WriteString("ERROR: failed\n")
+		}
+	}
+	count := 0
+	for f, err := range r.FindAllReader(strings.NewReader(log.String())) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Text != "ERROR: failed" || log.String()[f.Start:f.End] != f.Text {
+			t.Error("unexpected match", f)
+		}
+		count++
+	}
+	if count != 10 {
+		t.Error("expected 10 matches, got", count)
+	}
+
+	failing := io.MultiReader(strings.NewReader("abc"), iotest{})
+	f, err := r.FindReader(failing)
+	if f != nil || err == nil {
+		t.Error("expected read error, got", f, err)
+	}
+}
+
+func TestMatcherFindNext(t *testing.T) {
+	m := NewRegex("b+").Matcher()
+	f := m.FindNext("abbbc")
+	if f == nil || f.Start != 1 || f.End != 4 {
+		t.Error("'b+' did not find 'bbb' in 'abbbc':", f)
+	}
+}
+
+type iotest struct{}
+
+func (iotest) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
@@ -1,6 +1,9 @@
 package regex
 
 import (
+	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -59,3 +62,50 @@ func TestGenerator_WordGeneration(t *testing.T) {
 		println(r.Generate())
 	}
 }
+
+func TestGenerateWith(t *testing.T) {
+	patterns := []string{
+		"a*",
+		"([A-Z][0-9]){3}|([A-Z][0-9][A-Z] [0-9][A-Z][0-9])",
+		"(?i)[A-Z]{5}-[^ A-Za-z!#$#$#$#]{20}",
+		"\\b(if|else|[0-9]+)\\b",
+		"(:word_en:t) (:word_fr:u)",
+	}
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			generate := func() []string {
+				r := NewRegex(pattern)
+				rng := rand.New(rand.NewSource(42))
+				var generated []string
+				for i := 0; i < 20; i++ {
+					generated = append(generated, r.GenerateWith(rng))
+				}
+				return generated
+			}
+			first := generate()
+			for i := 0; i < 5; i++ {
+				if again := generate(); !slices.Equal(first, again) {
+					t.Fatalf("different strings generated with the same seed: %q and %q", first, again)
+				}
+			}
+			if !strings.HasPrefix(pattern, "(:") {
+				r := NewRegex(pattern)
+				for _, s := range first {
+					if !r.Match(s) {
+						t.Errorf("%q generated by %s does not match", s, pattern)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceAllGenerateWith(t *testing.T) {
+	r := NewRegex("[0-9]{3}")
+	generator := NewRegex("[0-9]{3}")
+	first := r.ReplaceAllGenerateWith("a 123 b 456", generator, rand.New(rand.NewSource(7)))
+	second := r.ReplaceAllGenerateWith("a 123 b 456", generator, rand.New(rand.NewSource(7)))
+	if first != second {
+		t.Errorf("different replacements with the same seed: %q and %q", first, second)
+	}
+}
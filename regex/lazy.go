@@ -0,0 +1,316 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// A lazy DFA builds the states of the DFA of a regular expression on demand, while
+// matching, instead of building the whole DFA when compiling. This avoids the cost of
+// the subset construction, and of the states it can create, for regular expressions
+// such as .{0,200}x whose DFA is much larger than their NFA.
+//
+// A state of the lazy DFA is a set of NFA states with the context of the previous
+// character, as in the subset construction (see dfa in automata.go), and its transition
+// on a character is computed when the character is first matched from the state. States
+// are kept in a cache of bounded size, evicting the least recently used, with the
+// transitions computed from them. When most states are not found in the cache, because
+// the input visits more states than the cache can hold, building and evicting states
+// costs more than it saves and the lazy DFA falls back to simulating the NFA.
+//
+// The matcher reports a partial match only for a prefix of a string matched, as with
+// the trimmed DFA. To check this without exploring the DFA, the NFA states from which a
+// final state can be reached are found when compiling, for each context of the previous
+// and of the next character, which is sufficient to decide if a set of NFA states can
+// lead to a full match.
+
+import (
+	"container/list"
+	"sync"
+)
+
+type (
+	// lazyDfa is the DFA of an NFA built on demand, with a cache of its states.
+	lazyDfa struct {
+		nfa      *automata
+		anchored bool
+		starts   map[context]*lazyState // start states by context of the previous character, never evicted
+
+		ids   map[state]int // numbers of the NFA states, for the keys of the states and liveness
+		width int           // number of liveness entries for each NFA state
+		live  []bool        // NFA states which can lead to a final state (see alive)
+
+		mutex    sync.Mutex
+		capacity int
+		cache    map[string]*list.Element
+		recent   list.List // cached states, most recently used first
+		lookups  int       // transitions looked up in the current window
+		misses   int       // transitions for which a state had to be built in the current window
+		simulate bool      // true when the cache thrashes and the NFA is simulated instead
+	}
+
+	// lazyState is a state of a lazy DFA.
+	lazyState struct {
+		states    set[state]
+		prev      context
+		key       string              // key of the state in the cache
+		finalNext set[context]        // contexts of the next character in which the state is final
+		next      map[rune]*lazyState // transitions computed from the state, nil for no transition
+		element   *list.Element       // element of the state in the cache, nil if it is not cached
+	}
+)
+
+const (
+	// defaultCacheSize is the size of the cache of a lazy DFA when no size is given.
+	defaultCacheSize = 1000
+
+	// thrashWindow is the number of lookups, as a multiple of the cache size, after which
+	// the cache is considered to thrash if most of them missed.
+	thrashWindow = 4
+
+	// anyNext is the liveness entry of an NFA state for any context of the next character.
+	anyNext = context(4)
+)
+
+// newLazyDfa returns the lazy DFA of the NFA, caching at most capacity states.
+func newLazyDfa(nfa *automata, capacity int) *lazyDfa {
+	if capacity <= 0 {
+		capacity = defaultCacheSize
+	}
+	d := &lazyDfa{
+		nfa:      nfa,
+		anchored: nfa.anchored(),
+		starts:   map[context]*lazyState{},
+		ids:      map[state]int{},
+		capacity: capacity,
+		cache:    map[string]*list.Element{},
+	}
+	d.findLive()
+
+	reachable := &set[state]{}
+	eClosure(nfa.start, nfa.Trans, reachable, nil)
+	start := d.newState(*reachable, atEdge)
+	for _, c := range contexts {
+		if d.anchored && c != atEdge {
+			d.starts[c] = d.newState(*reachable, c)
+		} else {
+			d.starts[c] = start
+		}
+	}
+	return d
+}
+
+// start returns the start state after a character in the prev context.
+func (d *lazyDfa) start(prev context) *lazyState {
+	return d.starts[prev]
+}
+
+// step returns the state reached from the state s on the character r, or nil if there
+// is no transition on r or the state reached cannot lead to a full match.
+func (d *lazyDfa) step(s *lazyState, r rune) *lazyState {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if t, ok := s.next[r]; ok && (t == nil || d.retained(t)) {
+		d.count(false)
+		if t != nil && t.element != nil {
+			d.recent.MoveToFront(t.element)
+		}
+		return t
+	}
+
+	next := atEdge
+	if d.anchored {
+		next = contextOf(r)
+	}
+	reachable := &set[state]{}
+	for q := range d.nfa.closure(s.states, s.prev, next, d.anchored) {
+		for c, t := range d.nfa.Trans[q] {
+			if !c.isEmpty() && c.match(r) {
+				eClosure(t, d.nfa.Trans, reachable, nil)
+			}
+		}
+	}
+	var t *lazyState
+	if len(*reachable) > 0 && d.alive(*reachable, next) {
+		t = d.lookup(*reachable, next)
+	}
+	if d.retained(s) {
+		if s.next == nil {
+			s.next = map[rune]*lazyState{}
+		}
+		s.next[r] = t
+	}
+	return t
+}
+
+// lookup returns the state for the NFA states reached after a character in the prev
+// context, from the cache if it is there, adding it to the cache otherwise.
+func (d *lazyDfa) lookup(states set[state], prev context) *lazyState {
+	if d.simulate {
+		return d.newState(states, prev)
+	}
+	key := dfaState{states, prev}.key(d.ids)
+	if e, ok := d.cache[key]; ok {
+		d.count(false)
+		d.recent.MoveToFront(e)
+		return e.Value.(*lazyState)
+	}
+	d.count(true)
+	s := d.newState(states, prev)
+	s.key = key
+	if d.simulate {
+		// the cache has just been found to thrash and was dropped
+		return s
+	}
+	s.element = d.recent.PushFront(s)
+	d.cache[key] = s.element
+	if d.recent.Len() > d.capacity {
+		evicted := d.recent.Remove(d.recent.Back()).(*lazyState)
+		delete(d.cache, evicted.key)
+		evicted.element, evicted.next = nil, nil
+	}
+	return s
+}
+
+// count records a lookup of a transition, and whether a state had to be built for it.
+// At the end of each window of lookups, the NFA is simulated from then on if most of
+// them missed the cache.
+func (d *lazyDfa) count(miss bool) {
+	d.lookups++
+	if miss {
+		d.misses++
+	}
+	if d.lookups < thrashWindow*d.capacity {
+		return
+	}
+	if 2*d.misses > d.lookups {
+		d.simulate = true
+		for e := d.recent.Front(); e != nil; e = e.Next() {
+			s := e.Value.(*lazyState)
+			s.element, s.next = nil, nil
+		}
+		for _, s := range d.starts {
+			s.next = nil
+		}
+		d.cache = nil
+		d.recent.Init()
+	}
+	d.lookups, d.misses = 0, 0
+}
+
+// retained returns true if the transitions computed from the state are kept, which is
+// when it is a start state or a state in the cache.
+func (d *lazyDfa) retained(s *lazyState) bool {
+	return !d.simulate && (s.element != nil || d.starts[s.prev] == s)
+}
+
+// newState returns a new state for the NFA states reached after a character in the
+// prev context, with the contexts of the next character in which it is final.
+func (d *lazyDfa) newState(states set[state], prev context) *lazyState {
+	s := &lazyState{states: states, prev: prev}
+	for _, next := range contexts {
+		if d.nfa.containsFinal(d.nfa.closure(states, prev, next, d.anchored)) {
+			if s.finalNext == nil {
+				s.finalNext = set[context]{}
+			}
+			s.finalNext[next] = true
+		}
+	}
+	return s
+}
+
+// findLive finds the NFA states from which a final state can be reached. A state of the
+// NFA can be live after a character in one context and before one in another but not
+// in others, due to assertions, so liveness is found for each state and pair of contexts,
+// as well as for each state and context of the previous character, whatever the next
+// context (anyNext). Without assertions, the contexts are irrelevant and only atEdge is
+// used.
+func (d *lazyDfa) findLive() {
+	for s := range d.nfa.Trans {
+		d.number(s)
+		for _, t := range d.nfa.Trans[s] {
+			d.number(t)
+		}
+	}
+	d.number(d.nfa.start)
+
+	prevs, nexts := []context{atEdge}, []context{atEdge}
+	d.width = 1
+	if d.anchored {
+		prevs, nexts = contexts, contexts
+		d.width = len(contexts) * (len(contexts) + 1)
+	}
+
+	// reverse[n] are the entries which are live if entry n is
+	reverse := make([][]int, len(d.ids)*d.width)
+	var pending []int
+	for s, id := range d.ids {
+		for _, prev := range prevs {
+			for _, next := range nexts {
+				from := d.entry(id, prev, next)
+				if d.anchored {
+					reverse[from] = append(reverse[from], d.entry(id, prev, anyNext))
+				}
+				if s == d.nfa.final[0] {
+					pending = append(pending, from)
+				}
+				for c, t := range d.nfa.Trans[s] {
+					to := d.entry(d.ids[t], prev, next)
+					if c.isEmpty() {
+						if a, ok := c.(*assertion); ok && !a.holds(prev, next) {
+							continue
+						}
+					} else {
+						spans := c.spanSet()
+						if spans != nil && len(spans) == 0 {
+							continue
+						}
+						if d.anchored {
+							if next == atEdge || (spans != nil && len(spans.intersection(next.spans())) == 0) {
+								continue
+							}
+							to = d.entry(d.ids[t], next, anyNext)
+						}
+					}
+					reverse[to] = append(reverse[to], from)
+				}
+			}
+		}
+	}
+
+	d.live = make([]bool, len(reverse))
+	for len(pending) > 0 {
+		n := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !d.live[n] {
+			d.live[n] = true
+			pending = append(pending, reverse[n]...)
+		}
+	}
+}
+
+// alive returns true if a final state can be reached from the NFA states after a
+// character in the prev context.
+func (d *lazyDfa) alive(states set[state], prev context) bool {
+	next := atEdge
+	if d.anchored {
+		next = anyNext
+	}
+	for s := range states {
+		if d.live[d.entry(d.ids[s], prev, next)] {
+			return true
+		}
+	}
+	return false
+}
+
+// entry returns the index of the liveness of the NFA state with the id, after a character
+// in the prev context and before one in the next context.
+func (d *lazyDfa) entry(id int, prev, next context) int {
+	return id*d.width + int(prev)*(len(contexts)+1) + int(next)
+}
+
+// number gives the next number to the NFA state if it does not have one.
+func (d *lazyDfa) number(s state) {
+	if _, ok := d.ids[s]; !ok {
+		d.ids[s] = len(d.ids)
+	}
+}
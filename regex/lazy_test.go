@@ -0,0 +1,143 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLazyMatchNext(t *testing.T) {
+	patterns := []string{
+		"a(b|c)*d",
+		"[0-9]+(\\.[0-9]+)?",
+		"(?i)hello",
+		"\\bfor\\b",
+		"x\\B[a-z]",
+		"a\\bb",
+		"(?m)^ab$\\n^c",
+		"(if|else)+",
+	}
+	inputs := []string{"", "abcbd", "ad", "abx", "12.5", "12.", ".5", "HeLLo", "hello!",
+		"for", "form", "xy", "x!", "ab", "ab\nc", "ifelseif", "ife"}
+	for _, pattern := range patterns {
+		eager := NewRegex(pattern)
+		for _, cacheSize := range []int{0, 2} {
+			lazy := MustCompile(pattern, Lenient(), Lazy(cacheSize))
+			for _, input := range inputs {
+				e, l := eager.Matcher(), lazy.Matcher()
+				for _, c := range input {
+					em, lm := e.MatchNext(c), l.MatchNext(c)
+					if em != lm || e.FullMatch.String() != l.FullMatch.String() ||
+						e.PartialMatch.String() != l.PartialMatch.String() {
+						t.Errorf("%s (cache %d) on %q at %q: lazy %v (%q, %q), expected %v (%q, %q)",
+							pattern, cacheSize, input, c, lm, l.FullMatch.String(), l.PartialMatch.String(),
+							em, e.FullMatch.String(), e.PartialMatch.String())
+						break
+					}
+				}
+				if eager.Match(input) != lazy.Match(input) {
+					t.Errorf("%s (cache %d): Match(%q) = %v", pattern, cacheSize, input, lazy.Match(input))
+				}
+			}
+		}
+	}
+}
+
+func TestLazyContexts(t *testing.T) {
+	r := MustCompile("\\bab\\b", Lazy(0))
+	m := r.Matcher()
+	m.ResetAfter('x')
+	if m.MatchNext('a') != NoMatch {
+		t.Errorf("matched after a word character")
+	}
+	m.ResetAfter(' ')
+	m.MatchNext('a')
+	if m.MatchNext('b') != FullMatch {
+		t.Errorf("ab not matched after a space")
+	}
+	if m.FullMatchBefore('c') || !m.FullMatchBefore('.') {
+		t.Errorf("FullMatchBefore does not respect the word boundary")
+	}
+}
+
+func TestLazyLarge(t *testing.T) {
+	r := MustCompile(".{0,200}x", Lazy(10))
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"x", true},
+		{"abc", false},
+		{strings.Repeat("a", 200) + "x", true},
+		{strings.Repeat("a", 201) + "x", false},
+		{strings.Repeat("x", 150), true},
+	}
+	for _, test := range tests {
+		if got := r.Match(test.input); got != test.expected {
+			t.Errorf("Match(%d characters) = %v, expected %v", len(test.input), got, test.expected)
+		}
+	}
+	m := r.Matcher()
+	for _, c := range strings.Repeat("a", 201) {
+		m.MatchNext(c)
+	}
+	if m.LastMatch != NoMatch {
+		t.Errorf("a prefix of 201 characters was matched")
+	}
+}
+
+func TestLazyThrash(t *testing.T) {
+	// the DFA has 512 states, far more than the cache can hold
+	r := MustCompile("[ab]*a[ab]{8}", Lazy(4))
+	rng := rand.New(rand.NewSource(1))
+	for range 50 {
+		s := make([]byte, 10+rng.Intn(40))
+		for i := range s {
+			s[i] = "ab"[rng.Intn(2)]
+		}
+		if expected := s[len(s)-9] == 'a'; r.Match(string(s)) != expected {
+			t.Errorf("Match(%q) = %v, expected %v", s, !expected, expected)
+		}
+	}
+	if !r.lazy.simulate {
+		t.Errorf("the cache thrashes but the NFA is not simulated")
+	}
+}
+
+func TestLazyOperations(t *testing.T) {
+	r := MustCompile("(?P<n>[0-9]+)|if", Lazy(0))
+	var found []string
+	for f := range r.FindAll("a 12 if 345") {
+		found = append(found, f.Text)
+	}
+	if expected := []string{"12", "if", "345"}; !slices.Equal(found, expected) {
+		t.Errorf("FindAll = %q, expected %q", found, expected)
+	}
+	if r.MatchEmpty() {
+		t.Errorf("MatchEmpty is true")
+	}
+	if s := r.Generate(); !r.Match(s) {
+		t.Errorf("generated %q is not matched", s)
+	}
+	if equivalent, _ := Equivalent(r, NewRegex("if|[0-9]+")); !equivalent {
+		t.Errorf("lazy regular expression is not equivalent to its eager version")
+	}
+}
+
+func TestLazyAutomaton(t *testing.T) {
+	for _, r := range []*Regex{MustCompile("if|[0-9]+", Lazy(0)), MustCompile("(:word_en:l)@x\\.com")} {
+		if r.Dfa != nil {
+			t.Errorf("%s: DFA built on compilation", r)
+		}
+		if d := r.Automaton(); d == nil || r.Dfa != d {
+			t.Errorf("%s: DFA not built by Automaton", r)
+		}
+	}
+	r := MustCompile("if|[0-9]+", Lazy(0))
+	if equivalent, s := Equivalent(r, NewRegexFromPattern(r.Automaton().Pattern())); !equivalent {
+		t.Errorf("pattern of the DFA differs from the lazy regular expression on %q", s)
+	}
+}
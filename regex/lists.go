@@ -0,0 +1,307 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Word lists, used in patterns as (:name), match and generate the words of the list
+// registered with that name. Lists are registered with RegisterList,
+// RegisterWeightedList or RegisterListFS, or are embedded in the package (word_en and
+// word_fr), in which case they are loaded when first used. A registered list replaces
+// an embedded one with the same name. Lists should be registered before compiling the
+// patterns using them, as their words are matched by a trie built when compiling.
+//
+// The trie of a list is the minimal deterministic automaton of its words, in which the
+// words share the states of their common suffixes as well as of their common prefixes.
+// It is built once for each conversion of the list, and copied into the NFA of each
+// pattern matching the list, so that the subset construction has no choices to resolve
+// in it and minimization little to merge.
+//
+// In list files, each line is a word, optionally followed by a tab and its weight.
+// Words are generated with a probability proportional to their weights, which are 1 if
+// not given, and empty lines are ignored.
+
+import (
+	"embed"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// WeightedWord is a word of a list with the weight giving its probability of being
+	// generated relative to the other words of the list.
+	WeightedWord struct {
+		Word   string
+		Weight float64
+	}
+
+	// wordList is a registered list of words, with the running totals of their weights,
+	// or nil if the words all have the same weight.
+	wordList struct {
+		words  []string
+		totals []float64
+
+		triesMutex sync.Mutex
+		tries      map[trieKey]*wordTrie // tries of the words by conversion, built when first needed
+	}
+
+	// trieKey identifies the trie of a list for the conversions of its words and whether
+	// they are compared ignoring case.
+	trieKey struct {
+		convert conversion
+		fold    bool
+	}
+
+	// wordTrie is the minimal deterministic automaton of the words of a list. Its nodes
+	// are numbered from 0, with their transitions sorted by character, and end marks the
+	// nodes ending a word.
+	wordTrie struct {
+		root  int
+		edges [][]trieEdge
+		end   []bool
+	}
+
+	// trieEdge is a transition of a trie on a character.
+	trieEdge struct {
+		char rune
+		to   int
+	}
+)
+
+//go:embed lists/*
+var lists embed.FS
+
+var (
+	registry      = map[string]*wordList{}
+	registryMutex sync.RWMutex
+)
+
+// RegisterList registers the words as the list with the name, which can then be used
+// in patterns as (:name). All words have the same probability of being generated.
+func RegisterList(name string, words []string) {
+	register(name, &wordList{words: slices.Clone(words)})
+}
+
+// RegisterWeightedList registers the words as the list with the name, with each word
+// generated with a probability proportional to its weight, such as the frequency of
+// a surname. It returns an error if a weight is negative or all weights are zero.
+func RegisterWeightedList(name string, words []WeightedWord) error {
+	l, err := weighted(words)
+	if err != nil {
+		return errors.New("regex: list " + name + ": " + err.Error())
+	}
+	register(name, l)
+	return nil
+}
+
+// RegisterListFS registers each file in the directory of the file system as a list
+// named after the file, without its extension. Each line of a file is a word, optionally
+// followed by a tab and its weight.
+func RegisterListFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		l, err := readList(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return errors.New("regex: list " + name + ": " + err.Error())
+		}
+		register(name, l)
+	}
+	return nil
+}
+
+func register(name string, l *wordList) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[name] = l
+}
+
+// lookupList returns the list registered with the name, loading it from the embedded
+// lists if it has not been registered, or false if there is no such list.
+func lookupList(name string) (*wordList, bool) {
+	registryMutex.RLock()
+	l, ok := registry[name]
+	registryMutex.RUnlock()
+	if ok {
+		return l, true
+	}
+	l, err := readList(lists, "lists/"+name)
+	if err != nil {
+		return nil, false
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if registered, ok := registry[name]; ok {
+		return registered, true
+	}
+	registry[name] = l
+	return l, true
+}
+
+// readList reads a list of words, one per line, with an optional weight after a tab.
+func readList(fsys fs.FS, file string) (*wordList, error) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	var words []WeightedWord
+	weightedWords := false
+	for i, line := range strings.Split(string(content), "\n") {
+		word, weight := strings.TrimSpace(line), 1.0
+		if tab := strings.LastIndexByte(line, '\t'); tab != -1 {
+			weight, err = strconv.ParseFloat(strings.TrimSpace(line[tab+1:]), 64)
+			if err != nil {
+				return nil, errors.New("invalid weight on line " + strconv.Itoa(i+1))
+			}
+			word, weightedWords = strings.TrimSpace(line[:tab]), true
+		}
+		if word != "" {
+			words = append(words, WeightedWord{word, weight})
+		}
+	}
+	if !weightedWords {
+		l := &wordList{}
+		for _, w := range words {
+			l.words = append(l.words, w.Word)
+		}
+		return l, nil
+	}
+	return weighted(words)
+}
+
+// weighted returns the list of the weighted words.
+func weighted(words []WeightedWord) (*wordList, error) {
+	l := &wordList{}
+	total := 0.0
+	for _, w := range words {
+		if w.Weight < 0 {
+			return nil, errors.New("negative weight for " + w.Word)
+		}
+		total += w.Weight
+		l.words = append(l.words, w.Word)
+		l.totals = append(l.totals, total)
+	}
+	if len(words) > 0 && total == 0 {
+		return nil, errors.New("all weights are zero")
+	}
+	return l, nil
+}
+
+// random returns a random word from the list, or an empty string if the list is empty.
+func (l *wordList) random(rng *rand.Rand) string {
+	switch {
+	case len(l.words) == 0:
+		return ""
+	case l.totals == nil:
+		return l.words[intn(rng, len(l.words))]
+	}
+	var r float64
+	if rng == nil {
+		r = rand.Float64()
+	} else {
+		r = rng.Float64()
+	}
+	r *= l.totals[len(l.totals)-1]
+	i, _ := slices.BinarySearch(l.totals, r)
+	// skip words with a zero weight, whose total is the same as the previous word's
+	for i < len(l.words)-1 && l.totals[i] <= r {
+		i++
+	}
+	return l.words[i]
+}
+
+// trie returns the trie of the words of the list after the conversion, in lowercase if
+// fold is true, building it when first requested.
+func (l *wordList) trie(convert func(string) string, key trieKey) *wordTrie {
+	l.triesMutex.Lock()
+	defer l.triesMutex.Unlock()
+	if t, ok := l.tries[key]; ok {
+		return t
+	}
+	words := make([]string, len(l.words))
+	for i, w := range l.words {
+		words[i] = convert(w)
+		if key.fold {
+			words[i] = strings.ToLower(words[i])
+		}
+	}
+	t := newWordTrie(words)
+	if l.tries == nil {
+		l.tries = map[trieKey]*wordTrie{}
+	}
+	l.tries[key] = t
+	return t
+}
+
+// newWordTrie returns the minimal deterministic automaton of the non-empty words. The
+// trie of the words is built first and its nodes are then numbered from the leaves up,
+// giving the same number to nodes which end a word alike and have the same transitions
+// to the same numbered nodes, as they match the same suffixes.
+func newWordTrie(words []string) *wordTrie {
+	type node struct {
+		children map[rune]*node
+		end      bool
+	}
+	root := &node{}
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		n := root
+		for _, r := range w {
+			child, ok := n.children[r]
+			if !ok {
+				child = &node{}
+				if n.children == nil {
+					n.children = map[rune]*node{}
+				}
+				n.children[r] = child
+			}
+			n = child
+		}
+		n.end = true
+	}
+
+	t := &wordTrie{}
+	numbers := map[string]int{}
+	var number func(n *node) int
+	number = func(n *node) int {
+		edges := make([]trieEdge, 0, len(n.children))
+		for r, child := range n.children {
+			edges = append(edges, trieEdge{r, number(child)})
+		}
+		slices.SortFunc(edges, func(a, b trieEdge) int { return int(a.char - b.char) })
+
+		signature := make([]byte, 0, 1+len(edges)*2*binary.MaxVarintLen32)
+		if n.end {
+			signature = append(signature, 1)
+		} else {
+			signature = append(signature, 0)
+		}
+		for _, e := range edges {
+			signature = binary.AppendUvarint(signature, uint64(e.char))
+			signature = binary.AppendUvarint(signature, uint64(e.to))
+		}
+		if id, ok := numbers[string(signature)]; ok {
+			return id
+		}
+		id := len(t.edges)
+		numbers[string(signature)] = id
+		t.edges = append(t.edges, edges)
+		t.end = append(t.end, n.end)
+		return id
+	}
+	t.root = number(root)
+	return t
+}
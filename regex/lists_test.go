@@ -0,0 +1,183 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRegisterList(t *testing.T) {
+	RegisterList("test_city", []string{"Port Louis", "Curepipe", "Mahebourg"})
+	r, err := Compile("(:test_city:u)-[0-9]{2}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		s := r.GenerateWith(rng)
+		if !slices.ContainsFunc([]string{"PORT LOUIS-", "CUREPIPE-", "MAHEBOURG-"}, func(prefix string) bool {
+			return len(s) == len(prefix)+2 && s[:len(prefix)] == prefix
+		}) {
+			t.Errorf("unexpected string generated: %q", s)
+		}
+	}
+}
+
+func TestRegisterWeightedList(t *testing.T) {
+	err := RegisterWeightedList("test_surname", []WeightedWord{{"Smith", 3}, {"Never", 0}, {"Jones", 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := MustCompile("(:test_surname)")
+	rng := rand.New(rand.NewSource(2))
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[r.GenerateWith(rng)]++
+	}
+	if counts["Never"] != 0 || len(counts) != 2 {
+		t.Errorf("unexpected words generated: %v", counts)
+	}
+	if ratio := float64(counts["Smith"]) / float64(counts["Jones"]); ratio < 2.6 || ratio > 3.4 {
+		t.Errorf("Smith generated %.2f times as often as Jones, expected 3", ratio)
+	}
+
+	if err := RegisterWeightedList("test_invalid", []WeightedWord{{"a", -1}}); err == nil {
+		t.Error("expected an error for a negative weight")
+	}
+	if err := RegisterWeightedList("test_invalid", []WeightedWord{{"a", 0}}); err == nil {
+		t.Error("expected an error for zero weights")
+	}
+}
+
+func TestRegisterListFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/test_product.txt": {Data: []byte("AB-100\nCD-200\n\n")},
+		"data/test_name":        {Data: []byte("Anna\t2\nBob\t0\n")},
+		"bad/test_bad":          {Data: []byte("x\tmany\n")},
+	}
+	if err := RegisterListFS(fsys, "data"); err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(3))
+	product := MustCompile("(:test_product)")
+	for i := 0; i < 10; i++ {
+		if s := product.GenerateWith(rng); s != "AB-100" && s != "CD-200" {
+			t.Errorf("unexpected product %q", s)
+		}
+	}
+	name := MustCompile("(:test_name)")
+	for i := 0; i < 10; i++ {
+		if s := name.GenerateWith(rng); s != "Anna" {
+			t.Errorf("unexpected name %q", s)
+		}
+	}
+	if err := RegisterListFS(fsys, "bad"); err == nil {
+		t.Error("expected an error for an invalid weight")
+	}
+	if err := RegisterListFS(fsys, "missing"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestUnknownList(t *testing.T) {
+	if _, err := Compile("(:test_unknown)"); err == nil {
+		t.Error("expected a syntax error for an unknown list")
+	}
+	if s := NewRegex("(:test_unknown)").Generate(); s != "" {
+		t.Errorf("expected an empty string for an unknown list, got %q", s)
+	}
+	if _, err := Compile("(:word_en:lt)"); err != nil {
+		t.Errorf("embedded list not found: %v", err)
+	}
+}
+
+func TestListMatch(t *testing.T) {
+	RegisterList("test_fruit", []string{"apple", "Apricot", "banana split", " kiwi "})
+	tests := []struct {
+		pattern  string
+		matched  []string
+		rejected []string
+	}{
+		{"(:test_fruit)", []string{"apple", "Apricot", "banana split", " kiwi "}, []string{"", "app", "Apple", "apricot", "kiwi", "apples"}},
+		{"(:test_fruit:l)", []string{"apple", "apricot", "banana split"}, []string{"Apricot", "APPLE"}},
+		{"(:test_fruit:u)", []string{"APPLE", "APRICOT", "BANANA SPLIT"}, []string{"apple"}},
+		{"(:test_fruit:t)", []string{"Apple", "Apricot", "Banana split"}, []string{"apple", "Banana Split"}},
+		{"(:test_fruit:m)", []string{"kiwi", "apple"}, []string{" kiwi "}},
+		{"(?i)(:test_fruit)", []string{"APPLE", "aPRicot", "Banana Split"}, []string{"banana"}},
+		{"(:test_fruit)@x\\.com", []string{"apple@x.com", "Apricot@x.com"}, []string{"pear@x.com", "apple@x"}},
+		{"(:test_fruit)(, (:test_fruit))*", []string{"apple", "apple, Apricot, apple"}, []string{"apple,", "apple, "}},
+		{"\\b(:test_fruit)\\b", []string{"apple"}, []string{" kiwi "}},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			r := MustCompile(test.pattern)
+			for _, s := range test.matched {
+				if !r.Match(s) {
+					t.Errorf("%q not matched", s)
+				}
+			}
+			for _, s := range test.rejected {
+				if r.Match(s) {
+					t.Errorf("%q matched", s)
+				}
+			}
+		})
+	}
+}
+
+func TestListMatchSpaces(t *testing.T) {
+	RegisterList("test_spaced", []string{"New   York"})
+	r := MustCompile("(:test_spaced:s)")
+	if !r.Match("New York") || r.Match("New   York") {
+		t.Errorf("single spaces not matched")
+	}
+}
+
+func TestWordTrie(t *testing.T) {
+	RegisterList("test_animal", []string{"cat", "cats", "bat", "bats", "Bat"})
+	l, _ := lookupList("test_animal")
+	trie := l.trie(func(w string) string { return w }, trieKey{fold: true})
+	// the root, then one node for each of "at(s)", "t(s)", "(s)" and the end of "s"
+	if len(trie.edges) != 5 {
+		t.Errorf("trie has %d nodes, expected 5", len(trie.edges))
+	}
+	if l.trie(nil, trieKey{fold: true}) != trie {
+		t.Errorf("trie not reused")
+	}
+	equivalent, _ := Equivalent(MustCompile("(?i)(:test_animal)"), MustCompile("(?i)[cb]ats?"))
+	if !equivalent {
+		t.Errorf("list not equivalent to its words")
+	}
+}
+
+func TestListMatchGenerated(t *testing.T) {
+	r := MustCompile("(:word_en:l)@x\\.com")
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 20; i++ {
+		if s := r.GenerateWith(rng); !r.Match(s) {
+			t.Errorf("%q generated but not matched", s)
+		}
+	}
+	if r.Match("notaword123@x.com") {
+		t.Error("word not in the list matched")
+	}
+}
+
+func TestListWeightsGeneration(t *testing.T) {
+	// generation picks whole words by weight, not characters along the trie
+	if err := RegisterWeightedList("test_prefix", []WeightedWord{{"a", 1}, {"ab", 1}, {"abc", 1}, {"abd", 97}}); err != nil {
+		t.Fatal(err)
+	}
+	r := MustCompile("(:test_prefix)")
+	rng := rand.New(rand.NewSource(6))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[r.GenerateWith(rng)]++
+	}
+	if counts["abd"] < 900 {
+		t.Errorf("abd generated %d times out of 1000, expected about 970", counts["abd"])
+	}
+}
@@ -3,6 +3,8 @@
 package regex
 
 import (
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -13,9 +15,14 @@ type (
 		LastMatch    MatchType
 		FullMatch    strings.Builder
 		PartialMatch strings.Builder
-		Groups       map[int]*strings.Builder
 		Compiled     *Regex
 		State        state
+
+		lazy     *lazyState // state of the lazy DFA, if the regular expression is compiled with Lazy
+		pos      int        // byte offset of the next character in the input
+		prev     context    // context of the previous character
+		threads  []thread   // threads of the TNFA simulation, for regex with capturing groups
+		captures []int      // positions of groups in the last full match
 	}
 )
 
@@ -35,12 +42,35 @@ const (
 	Start
 )
 
+// Reset prepares the matcher to match a new input from its start.
 func (m *Matcher) Reset() {
+	if m.Compiled.lazy != nil {
+		m.reset(nil, m.Compiled.lazy.start(atEdge), atEdge)
+	} else {
+		m.reset(m.Compiled.Dfa.start, nil, atEdge)
+	}
+}
+
+// ResetAfter prepares the matcher to match text that follows the character prev
+// in the input. The start state depends on the context of prev: ^ does not match
+// and \b and \B match depending on whether prev is a word character or not. The
+// context of each subsequent character is then carried by the state of the DFA.
+func (m *Matcher) ResetAfter(prev rune) {
+	c := contextOf(prev)
+	if m.Compiled.lazy != nil {
+		m.reset(nil, m.Compiled.lazy.start(c), c)
+	} else {
+		m.reset(m.Compiled.Dfa.starts[c], nil, c)
+	}
+}
+
+func (m *Matcher) reset(start state, lazy *lazyState, prev context) {
 	m.LastMatch = Start
 	m.FullMatch.Reset()
 	m.PartialMatch.Reset()
-	m.Groups = make(map[int]*strings.Builder)
-	m.State = m.Compiled.Dfa.start
+	m.State = start
+	m.lazy = lazy
+	m.begin(prev)
 }
 
 func (m *Matcher) Match(input string) bool {
@@ -50,59 +80,97 @@ func (m *Matcher) Match(input string) bool {
 		}
 	}
 	//return slices.Index(m.Compiled.Dfa.final, m.State) != -1
-	return m.Compiled.Dfa.finalMap[m.State]
+	return m.final(atEdge)
 }
 
-func (m *Matcher) FindNext(input string) bool {
-	for _, c := range input {
-		if m.MatchNext(c) == NoMatch {
-			return false
-		}
-	}
-	//return slices.Index(m.Compiled.Dfa.final, m.State) != -1
-	return m.Compiled.Dfa.finalMap[m.State]
+// FullMatchBefore returns true if the input supplied so far is a full match when it
+// is followed by the character next in the text. This differs from a FullMatch
+// returned by MatchNext only for assertions that depend on the next character.
+func (m *Matcher) FullMatchBefore(next rune) bool {
+	return m.LastMatch != NoMatch && m.final(contextOf(next))
 }
 
+// MatchNext supplies the next character to the matcher and returns the kind of
+// match of the input so far. A full match is reported if the input matches the
+// regular expression when it ends after r; assertions that depend on the following
+// character (such as $ and \b) are considered at the end of the input.
 func (m *Matcher) MatchNext(r rune) MatchType {
 	if m.LastMatch != NoMatch {
-		trans := m.Compiled.Dfa.Trans[m.State]
-		for c, t := range trans {
-			if c.match(r) {
-				m.State = t
-				//if slices.Index(m.Compiled.Dfa.final, t) == -1 {
-				if !m.Compiled.Dfa.finalMap[t] {
-					if m.LastMatch == FullMatch {
-						m.PartialMatch.Reset()
-						m.PartialMatch.WriteString(m.FullMatch.String())
-					}
-					m.PartialMatch.WriteRune(r)
-					m.LastMatch = PartialMatch
-				} else {
-					if m.LastMatch == PartialMatch {
-						m.FullMatch.Reset()
-						m.FullMatch.WriteString(m.PartialMatch.String())
-					}
-					m.FullMatch.WriteRune(r)
-					m.LastMatch = FullMatch
+		if m.next(r) {
+			if !m.final(atEdge) {
+				if m.LastMatch == FullMatch {
+					m.PartialMatch.Reset()
+					m.PartialMatch.WriteString(m.FullMatch.String())
 				}
-				groupSet := set[int]{}
-				groups := c.groups()
-				for g := groups.Front(); g != nil; g = g.Next() {
-					group := g.Value.(int)
-					if group != 0 {
-						groupSet[group] = true
-					}
-					s, ok := m.Groups[g.Value.(int)]
-					if !ok {
-						s = &strings.Builder{}
-						m.Groups[group] = s
-					}
-					s.WriteRune(r)
+				m.PartialMatch.WriteRune(r)
+				m.LastMatch = PartialMatch
+			} else {
+				if m.LastMatch == PartialMatch {
+					m.FullMatch.Reset()
+					m.FullMatch.WriteString(m.PartialMatch.String())
 				}
-				return m.LastMatch
+				m.FullMatch.WriteRune(r)
+				m.LastMatch = FullMatch
 			}
+			m.advance(r)
+			return m.LastMatch
 		}
 		m.LastMatch = NoMatch
 	}
 	return m.LastMatch
 }
+
+// Expected returns the characters which can follow the input supplied so far, as the
+// sorted strings of the characters of the transitions from the state of the matcher.
+func (m *Matcher) Expected() []string {
+	expected := set[string]{}
+	if m.Compiled.lazy != nil {
+		d := m.Compiled.lazy
+		for _, next := range contexts {
+			for s := range d.nfa.closure(m.lazy.states, m.lazy.prev, next, d.anchored) {
+				for c := range d.nfa.Trans[s] {
+					if !c.isEmpty() {
+						expected[c.String()] = true
+					}
+				}
+			}
+		}
+	} else {
+		for c := range m.Compiled.Dfa.Trans[m.State] {
+			expected[c.String()] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(expected))
+}
+
+// next moves the matcher to the state reached on the character r, returning false
+// if there is no transition on r.
+func (m *Matcher) next(r rune) bool {
+	if m.Compiled.lazy != nil {
+		t := m.Compiled.lazy.step(m.lazy, r)
+		if t == nil {
+			return false
+		}
+		m.lazy = t
+		return true
+	}
+	for c, t := range m.Compiled.Dfa.Trans[m.State] {
+		if c.match(r) {
+			m.State = t
+			return true
+		}
+	}
+	return false
+}
+
+// final returns true if the state of the matcher is final when followed by a character
+// in the next context, or when at the end of the input if next is atEdge.
+func (m *Matcher) final(next context) bool {
+	if m.Compiled.lazy != nil {
+		return m.lazy.finalNext[next]
+	}
+	if next == atEdge {
+		return m.Compiled.Dfa.finalMap[m.State]
+	}
+	return m.Compiled.Dfa.finalNext[m.State][next]
+}
@@ -0,0 +1,107 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestModifiers(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"(?i:abc)def", "ABCdef", true},
+		{"(?i:abc)def", "abcDEF", false},
+		{"a(?i)b", "aB", true},
+		{"a(?i)b", "AB", false},
+		{"(a(?i)b)c", "aBc", true},
+		{"(a(?i)b)c", "aBC", false},
+		{"(?i)a|b", "B", true},
+		{"(?i)ab(?-i)c", "ABc", true},
+		{"(?i)ab(?-i)c", "ABC", false},
+		{"(?i)a(?-i:b)c", "AbC", true},
+		{"(?i)a(?-i:b)c", "ABC", false},
+		{"(?is)a.b", "A\nB", true},
+		{"(?i-s)a.b", "A\nB", false},
+		{"(?:ab)+", "abab", true},
+		{"(?:ab)+", "aba", false},
+		{"(?:ab*)?", "", true},
+		{"(?:ab*)?", "abb", true},
+		{"(?:ab*)?", "b", false},
+		{"(?:ab*)*", "ba", false},
+		{"(?i:ab*)?", "AB", true},
+		{"(?i:ab*)?", "B", false},
+	}
+	for _, test := range tests {
+		r, err := Compile(test.pattern)
+		if err != nil {
+			t.Error(err)
+		} else if r.Match(test.input) != test.match {
+			t.Errorf("%q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+	}
+}
+
+func TestNonCapturingGroup(t *testing.T) {
+	r := MustCompile("(?:a|b)(c)")
+	if names := r.SubexpNames(); len(names) != 2 {
+		t.Error("non-capturing group was numbered:", names)
+	}
+	f := r.Find("xbc")
+	if f == nil || f.Groups[1].Text != "c" {
+		t.Error("'(?:a|b)(c)' did not capture group 1 in 'xbc':", f)
+	}
+}
+
+func TestDotAll(t *testing.T) {
+	if NewRegex(".").Match("\n") {
+		t.Error("'.' matched newline")
+	}
+	if !NewRegex("(?s).").Match("\n") {
+		t.Error("'(?s).' did not match newline")
+	}
+	var lines []string
+	for f := range NewRegex(".+").FindAll("ab\ncd") {
+		lines = append(lines, f.Text)
+	}
+	if len(lines) != 2 || lines[0] != "ab" || lines[1] != "cd" {
+		t.Error("'.+' did not find the lines of 'ab\\ncd':", lines)
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	var lines []string
+	for f := range NewRegex("(?m)^\\w+$").FindAll("ab\ncd e\nfg") {
+		lines = append(lines, f.Text)
+	}
+	if len(lines) != 2 || lines[0] != "ab" || lines[1] != "fg" {
+		t.Error("'(?m)^\\w+$' did not find the lines 'ab' and 'fg':", lines)
+	}
+	if NewRegex("^\\w+$").Find("ab\ncd") != nil {
+		t.Error("'^\\w+$' matched a line without multiline mode")
+	}
+	if !NewRegex("(?m)a$\n^b").Match("a\nb") {
+		t.Error("'(?m)a$<newline>^b' did not match 'a\\nb'")
+	}
+}
+
+func TestFreeSpacing(t *testing.T) {
+	r := MustCompile(`(?x)
+		(?P<year> \d{4} )  # year
+		-
+		(?P<month> \d{2} ) # month
+		\ end
+	`)
+	m := r.Matcher()
+	if !m.Match("2025-10 end") {
+		t.Fatal("free-spacing pattern did not match '2025-10 end'")
+	}
+	if g := m.Group("month"); g == nil || g.Text != "10" {
+		t.Error("group 'month' was not captured:", g)
+	}
+	if !MustCompile("(?x: a b )c d").Match("abc d") {
+		t.Error("free-spacing was not scoped to its group")
+	}
+}
@@ -0,0 +1,103 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Strings which are not matched by a regular expression are generated as near misses
+// of strings which are. A string matched is generated from the disjoint DFA of the
+// regular expression and is then either cut after a random prefix and followed by a
+// character with no transition from the state reached by the prefix, or changed by a
+// single edit: a character deleted, inserted or replaced, or two adjacent characters
+// swapped. As an edit can give another string matched, the result is checked and a
+// new attempt made if needed. When all attempts fail, such as for a regular expression
+// matching nearly all strings, a string is generated from the complement of the
+// regular expression instead.
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// nearMissAttempts is the number of near misses tried before generating from the complement.
+const nearMissAttempts = 20
+
+// GenerateNonMatching returns a random string which is not matched by the regular
+// expression, using the default source of math/rand. It returns false if the regular
+// expression matches all strings.
+func (r *Regex) GenerateNonMatching() (string, bool) {
+	return r.GenerateNonMatchingWith(nil)
+}
+
+// GenerateNonMatchingWith returns a random string which is not matched by the regular
+// expression, using the random number generator for all random choices (see GenerateWith).
+// The string is a near miss of a string matched: a prefix of it followed by a character
+// which cannot follow the prefix, or the string with a single edit. It returns false
+// if the regular expression matches all strings.
+func (r *Regex) GenerateNonMatchingWith(rng *rand.Rand) (string, bool) {
+	dfa := r.automaton().disjoint().trim()
+	valid := &Regex{Pattern: r.Pattern, Dfa: dfa}
+	for range nearMissAttempts {
+		s := []rune(valid.GenerateWith(rng))
+		var miss []rune
+		if intn(rng, 2) == 0 {
+			miss = dfa.illegalAfterPrefix(s, rng)
+		} else {
+			miss = edit(s, rng)
+		}
+		if miss != nil && !valid.Match(string(miss)) {
+			return string(miss), true
+		}
+	}
+	complement := r.Complement()
+	if complement.MatchNone() {
+		return "", false
+	}
+	return complement.GenerateWith(rng), true
+}
+
+// illegalAfterPrefix returns a random prefix of the string accepted by the trimmed,
+// disjoint DFA followed by a character with no transition from the state reached by
+// the prefix, preferably a printable ASCII character. It returns nil if all characters
+// have a transition from that state.
+func (auto *automata) illegalAfterPrefix(s []rune, rng *rand.Rand) []rune {
+	k := intn(rng, len(s)+1)
+	state := auto.start
+	for _, c := range s[:k] {
+		for ch, t := range auto.Trans[state] {
+			if ch.spanSet().match(c) {
+				state = t
+				break
+			}
+		}
+	}
+	var allowed spanSet
+	for ch := range auto.Trans[state] {
+		allowed = append(allowed, ch.spanSet()...)
+	}
+	illegal := allowed.invertAsciiPrintable()
+	if len(illegal) == 0 {
+		illegal = allowed.invertUnicode()
+	}
+	if len(illegal) == 0 {
+		return nil
+	}
+	return append(slices.Clone(s[:k]), illegal.random(rng))
+}
+
+// edit returns a copy of the string with a random edit: a character deleted, inserted or
+// replaced, or two adjacent characters swapped. Characters inserted or replacing another
+// are printable ASCII characters.
+func edit(s []rune, rng *rand.Rand) []rune {
+	s = slices.Clone(s)
+	i := intn(rng, len(s)+1)
+	switch op := intn(rng, 4); {
+	case op == 0 && i < len(s):
+		return slices.Delete(s, i, i+1)
+	case op == 1 && i < len(s):
+		s[i] = asciiPrintable.random(rng)
+		return s
+	case op == 2 && i+1 < len(s):
+		s[i], s[i+1] = s[i+1], s[i]
+		return s
+	}
+	return slices.Insert(s, i, asciiPrintable.random(rng))
+}
@@ -0,0 +1,52 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGenerateNonMatching(t *testing.T) {
+	patterns := []string{
+		"[0-9]{3}-[0-9]{4}",
+		"[a-z]+@[a-z]+\\.com",
+		"if|else|for",
+		"\\b[A-Z][a-z]*\\b",
+		"x*",
+		"(?s).+",
+	}
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			r := NewRegex(pattern)
+			rng := rand.New(rand.NewSource(5))
+			for i := 0; i < 50; i++ {
+				s, ok := r.GenerateNonMatchingWith(rng)
+				if !ok {
+					t.Fatal("no string generated")
+				}
+				if r.Match(s) {
+					t.Fatalf("%q generated is matched", s)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateNonMatchingNearMiss(t *testing.T) {
+	// near misses of 3 digits differ from them by at most one character
+	r := NewRegex("[0-9]{3}")
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 50; i++ {
+		s, _ := r.GenerateNonMatchingWith(rng)
+		if n := len([]rune(s)); n > 4 {
+			t.Errorf("%q is not a near miss", s)
+		}
+	}
+}
+
+func TestGenerateNonMatchingAll(t *testing.T) {
+	if s, ok := NewRegex("(?s).*").GenerateNonMatching(); ok {
+		t.Errorf("%q generated for a regular expression matching all strings", s)
+	}
+}
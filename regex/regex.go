@@ -6,13 +6,13 @@
 package regex
 
 import (
-	"container/list"
-	"maps"
-	"math"
+	"fmt"
 	"math/rand"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode"
 )
 
 type (
@@ -24,7 +24,14 @@ type (
 
 	Regex struct {
 		Pattern Pattern
-		Dfa     *automata
+		Dfa     *automata // nil until it is needed if compiled with Lazy or with word lists (see Automaton)
+
+		tnfa           *tnfa     // for finding capturing groups, nil if there is none
+		lists          bool      // true if the pattern has word lists, generated from a separate DFA
+		generation     *automata // for generating strings with word lists, built when first needed (see generationDfa)
+		lazy           *lazyDfa  // for matching with states built on demand, nil unless compiled lazily
+		dfaOnce        sync.Once // builds the DFA of a lazy regular expression
+		generationOnce sync.Once // builds the DFA for generation
 	}
 
 	// choice represents the regex | regex rule
@@ -53,48 +60,248 @@ type (
 		re Pattern
 	}
 
-	// oneOrMore is for positive closure (re+)
+	// repeat is for a bounded repetition of a regular expression (re{min,max}), or
+	// an unbounded one (re{min,}) in which case max is ignored.
 	repeat struct {
-		re       Pattern
-		min, max uint8
+		re        Pattern
+		min, max  int
+		unbounded bool
 	}
 
-	// captureGrp is for grouping regular expressions inside brackets, i.e., (re)
+	// captureGrp is for grouping regular expressions inside brackets, i.e., (re),
+	// optionally named, i.e., (?P<name>re) or (?<name>re)
 	captureGroup struct {
-		re Pattern
+		re    Pattern
+		group int
+		name  string
 	}
 )
 
+// metaCharacters are the characters with a special meaning in regular expressions.
+const metaCharacters = "\\()[]{}|+*?.^$#-"
+
+// Escape returns the string with all metacharacters and whitespace escaped, so that
+// the regular expression returned matches the string literally, even in sets and in
+// free-spacing mode.
 func Escape(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, "(", "\\(")
-	s = strings.ReplaceAll(s, ")", "\\)")
-	s = strings.ReplaceAll(s, "[", "\\[")
-	s = strings.ReplaceAll(s, "]", "\\]")
-	s = strings.ReplaceAll(s, "{", "\\{")
-	s = strings.ReplaceAll(s, "}", "\\}")
-	s = strings.ReplaceAll(s, "|", "\\|")
-	s = strings.ReplaceAll(s, "+", "\\+")
-	s = strings.ReplaceAll(s, "*", "\\*")
-	s = strings.ReplaceAll(s, "?", "\\?")
-	return s
-}
-
-// NewRegex creates a new regular expression from the input
-func NewRegex(input string) *Regex {
+	var escaped strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(metaCharacters, c) || unicode.IsSpace(c) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
+
+// escapeChar returns the character as written in a regular expression: metacharacters
+// are escaped, or the characters with a special meaning in character sets if inSet is
+// true, and characters which are not printable are written as escape sequences.
+func escapeChar(c rune, inSet bool) string {
+	switch c {
+	case '\n':
+		return "\\n"
+	case '\t':
+		return "\\t"
+	case '\r':
+		return "\\r"
+	case '\f':
+		return "\\f"
+	case '\v':
+		return "\\v"
+	}
+	switch {
+	case !unicode.IsPrint(c) && c <= 0xff:
+		return fmt.Sprintf("\\x%02x", c)
+	case !unicode.IsPrint(c):
+		return fmt.Sprintf("\\x{%x}", c)
+	case inSet && strings.ContainsRune("\\[]-^", c):
+		return "\\" + string(c)
+	case !inSet && c != '-' && strings.ContainsRune(metaCharacters, c):
+		return "\\" + string(c)
+	}
+	return string(c)
+}
+
+// Option is an option for compiling regular expressions.
+type Option func(*options)
+
+type options struct {
+	lenient   bool
+	lazy      bool
+	cacheSize int
+}
+
+// Lenient parsing never fails, interpreting invalid patterns as best as it can: brackets
+// which are not closed are closed at the end of the pattern, a trailing backslash is
+// taken literally, text after an unmatched closing bracket is ignored, and invalid
+// repetition counts are adjusted. This is the behaviour of NewRegex.
+func Lenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// Lazy compilation builds the states of the DFA while matching, as they are reached,
+// instead of building the whole DFA when compiling, keeping at most cacheSize states
+// (or a default number if cacheSize is not positive) and evicting the least recently
+// used. If the input visits far more states than the cache can hold, matching falls
+// back to simulating the NFA. This suits regular expressions whose DFA would be too
+// large to build, such as .{0,200}x, at the cost of slower matching. Operations needing
+// the whole DFA, such as generating strings or combining regular expressions, build it
+// when first used.
+func Lazy(cacheSize int) Option {
+	return func(o *options) {
+		o.lazy = true
+		o.cacheSize = cacheSize
+	}
+}
+
+// Compile parses the pattern and returns the regular expression, or a *SyntaxError
+// if the pattern is not valid.
+func Compile(pattern string, opts ...Option) (*Regex, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	group := 0
-	groups := list.New()
-	groups.PushBack(0)
-	parser := parser{[]rune(input), 0, &group, groups}
-	r := parser.regex(&modifier{caseInsensitive: false, unicode: false})
-	n := r.nfa()
-	d := n.dfa().minimize()
-	//d := n.dfa()
-	return &Regex{r, d}
+	parser := parser{input: []rune(pattern), groups: &group, strict: !o.lenient}
+	r := parser.regex(&modifier{})
+	if parser.hasMore() {
+		// only an unmatched closing bracket stops the parsing before the end
+		parser.fail("end of pattern")
+	}
+	if parser.err != nil {
+		return nil, parser.err
+	}
+	return compile(r, group, o), nil
+}
+
+// compile builds the automata of the pattern, which has the given number of capturing
+// groups, numbered from 1 in order of their opening brackets.
+func compile(p Pattern, groups int, o options) *Regex {
+	n := p.nfa()
+	r := &Regex{Pattern: p, lists: hasList(p)}
+	if o.lazy || r.lists {
+		// the DFA of word lists is large, while matching visits few of its states
+		r.lazy = newLazyDfa(n, o.cacheSize)
+	} else {
+		r.Dfa = n.dfa().minimize()
+	}
+	if groups > 0 {
+		r.tnfa = newTnfa(n, groups)
+	}
+	return r
+}
+
+// Automaton returns the DFA of the regular expression, building it if the regular
+// expression was compiled with Lazy or has word lists, as its Dfa is nil until then.
+func (r *Regex) Automaton() *automata {
+	return r.automaton()
+}
+
+// automaton returns the DFA of the regular expression, which is built when first
+// needed if the regular expression was compiled with Lazy or has word lists.
+func (r *Regex) automaton() *automata {
+	if r.lazy != nil {
+		r.dfaOnce.Do(func() {
+			r.Dfa = r.lazy.nfa.dfa().minimize()
+		})
+	}
+	return r.Dfa
+}
+
+// generationDfa returns the DFA from which strings are generated. Word lists are
+// matched by the characters of their words in the DFA of the regular expression, but
+// generate a whole word in a single transition, respecting the weights of the words,
+// in the DFA built for generation, when first needed, if the pattern has word lists.
+func (r *Regex) generationDfa() *automata {
+	if !r.lists {
+		return r.automaton()
+	}
+	r.generationOnce.Do(func() {
+		r.generation = wholeWords(r.Pattern).nfa().dfa().minimize()
+	})
+	return r.generation
+}
+
+// hasList returns true if the pattern contains a word list.
+func hasList(p Pattern) bool {
+	found := false
+	Walk(p, func(n Node) bool {
+		found = found || n.Op == OpList
+		return !found
+	})
+	return found
+}
+
+// wholeWords returns a copy of the pattern in which word lists generate whole words.
+func wholeWords(p Pattern) Pattern {
+	switch p := p.(type) {
+	case *choice:
+		return &choice{wholeWords(p.left), wholeWords(p.right)}
+	case *sequence:
+		s := make([]Pattern, len(p.sequence))
+		for i, e := range p.sequence {
+			s[i] = wholeWords(e)
+		}
+		return &sequence{s}
+	case *zeroOrOne:
+		return &zeroOrOne{wholeWords(p.opt)}
+	case *zeroOrMore:
+		return &zeroOrMore{wholeWords(p.re)}
+	case *oneOrMore:
+		return &oneOrMore{wholeWords(p.re)}
+	case *repeat:
+		return &repeat{wholeWords(p.re), p.min, p.max, p.unbounded}
+	case *captureGroup:
+		return &captureGroup{wholeWords(p.re), p.group, p.name}
+	case *inList:
+		whole := *p
+		whole.whole = true
+		return &whole
+	default:
+		return p
+	}
+}
+
+// MustCompile is like Compile but panics if the pattern is not valid.
+func MustCompile(pattern string, opts ...Option) *Regex {
+	r, err := Compile(pattern, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// NewRegex creates a new regular expression from the input, parsed leniently.
+func NewRegex(input string) *Regex {
+	return MustCompile(input, Lenient())
+}
+
+// SubexpNames returns the names of the capturing groups of the regular expression,
+// indexed by group number. Unnamed groups, and group 0 (the whole match), have an
+// empty name.
+func (r *Regex) SubexpNames() []string {
+	if r.tnfa == nil {
+		return []string{""}
+	}
+	return slices.Clone(r.tnfa.names)
+}
+
+// SubexpIndex returns the number of the first group with the given name, or -1 if
+// there is no such group.
+func (r *Regex) SubexpIndex(name string) int {
+	if name != "" && r.tnfa != nil {
+		return slices.Index(r.tnfa.names, name)
+	}
+	return -1
 }
 
 func (r *Regex) Matcher() *Matcher {
-	return &Matcher{LastMatch: Start, Groups: map[int]*strings.Builder{}, Compiled: r, State: r.Dfa.start}
+	m := &Matcher{Compiled: r}
+	m.Reset()
+	return m
 }
 
 func (r *Regex) Match(input string) bool {
@@ -102,30 +309,41 @@ func (r *Regex) Match(input string) bool {
 }
 
 func (r *Regex) MatchEmpty() bool {
+	if r.lazy != nil {
+		return r.lazy.start(atEdge).finalNext[atEdge]
+	}
 	return r.Dfa.finalMap[r.Dfa.start]
 }
 
+// Generate returns a random string matched by the regular expression, using the
+// default source of math/rand.
 func (r *Regex) Generate() string {
+	return r.GenerateWith(nil)
+}
+
+// GenerateWith returns a random string matched by the regular expression, using the
+// random number generator for all random choices, or the default source of math/rand
+// if it is nil. Transitions are chosen in order of their characters, so that the same
+// regular expression and the same seed always generate the same strings.
+func (r *Regex) GenerateWith(rng *rand.Rand) string {
+	dfa := r.generationDfa()
 	var s strings.Builder
-	state := r.Dfa.start
-	trans := r.Dfa.Trans[state]
+	state := dfa.start
+	trans := dfa.sortedTransitions(state)
 	for len(trans) > 0 {
 		nextStates := len(trans)
-		final := slices.Index(r.Dfa.final, state) != -1
+		final := dfa.finalMap[state]
 		if final {
 			nextStates += 1
 		}
-		n := rand.Intn(nextStates)
+		n := intn(rng, nextStates)
 		if final && n == nextStates-1 {
 			break
 		} else {
-			t := slices.Collect(maps.Keys(trans))
-			c := t[n]
-			s.WriteString(c.random())
-			//s.WriteRune(c.spanSet().random())
-			state = trans[c]
+			s.WriteString(trans[n].char.random(rng))
+			state = trans[n].to
 		}
-		trans = r.Dfa.Trans[state]
+		trans = dfa.sortedTransitions(state)
 	}
 	return s.String()
 }
@@ -140,6 +358,36 @@ func (c *choice) String() string {
 	return c.left.String() + "|" + c.right.String()
 }
 
+// precedence returns the precedence of the operator of the pattern when written as a
+// string: 0 for a choice, 1 for a sequence, 2 for repetitions and 3 for characters and
+// groups. An operand with a lower precedence than its operator is written in a group.
+func precedence(p Pattern) int {
+	switch p := p.(type) {
+	case *choice:
+		return 0
+	case *sequence:
+		if len(p.sequence) == 1 {
+			return precedence(p.sequence[0])
+		}
+		return 1
+	case *zeroOrOne, *zeroOrMore, *oneOrMore, *repeat:
+		return 2
+	case *combination:
+		return precedence(p.eliminated())
+	default:
+		return 3
+	}
+}
+
+// operand returns the string of the pattern as the operand of an operator with the
+// given precedence, in a non-capturing group if its own precedence is lower.
+func operand(p Pattern, prec int) string {
+	if precedence(p) < prec {
+		return "(?:" + p.String() + ")"
+	}
+	return p.String()
+}
+
 // automata constructs a finite automaton for the choice (union) of two regular expressions.
 //
 //	    left
@@ -162,9 +410,9 @@ func (c *choice) nfa() *automata {
 	a.merge(left)
 	a.merge(right)
 
-	a.addTransitions(a.start, map[char]state{&empty{}: left.start, &empty{}: right.start})
-	a.addTransitions(left.final[0], map[char]state{&empty{}: a.final[0]})
-	a.addTransitions(right.final[0], map[char]state{&empty{}: a.final[0]})
+	a.addTransitions(a.start, map[char]state{epsilon(): left.start, epsilon(): right.start})
+	a.addTransitions(left.final[0], map[char]state{epsilon(): a.final[0]})
+	a.addTransitions(right.final[0], map[char]state{epsilon(): a.final[0]})
 
 	return &a
 }
@@ -172,7 +420,7 @@ func (c *choice) nfa() *automata {
 func (s *sequence) String() string {
 	ret := ""
 	for _, re := range s.sequence {
-		ret += re.String()
+		ret += operand(re, 1)
 	}
 	return ret
 }
@@ -197,7 +445,7 @@ func (s *sequence) nfa() *automata {
 			a.start = reAutomata.start
 			first = false
 		} else {
-			a.addTransitions(a.final[0], map[char]state{&empty{}: reAutomata.start})
+			a.addTransitions(a.final[0], map[char]state{epsilon(): reAutomata.start})
 		}
 		a.final = reAutomata.final
 	}
@@ -208,45 +456,64 @@ func (s *sequence) nfa() *automata {
 }
 
 func (r *zeroOrOne) String() string {
-	return r.opt.String() + "?"
+	return operand(r.opt, 3) + "?"
 	//return "?(" + r.opt.Pattern() + ")"
 }
 
-// automata constructs and returns an NFA for an optional subpattern.
+// automata constructs and returns an NFA for an optional subpattern. The subpattern
+// is enclosed in new start and final states, as its own start state can be reached
+// again inside it (as in ab*), where skipping to the final state would be wrong.
 //
-//	    _______________
-//	   /               \
-//	  /                 v
-//	start --> ... --> final
+//	    ______________________________
+//	   /                              \
+//	  /                                v
+//	start --> start' --> ... --> final' --> final
 func (r *zeroOrOne) nfa() *automata {
+	a := automata{
+		Trans: make(transitions),
+		start: &stateObj{},
+		final: []state{&stateObj{}},
+	}
 	opt := r.opt.nfa()
-	opt.addTransitions(opt.start, map[char]state{&empty{}: opt.final[0]})
-	return opt
+	a.merge(opt)
+	a.addTransitions(a.start, map[char]state{epsilon(): opt.start})
+	a.addTransitions(a.start, map[char]state{epsilon(): a.final[0]})
+	a.addTransitions(opt.final[0], map[char]state{epsilon(): a.final[0]})
+	return &a
 }
 
 func (r *zeroOrMore) String() string {
-	return r.re.String() + "*"
+	return operand(r.re, 3) + "*"
 	//return "*(" + r.re.Pattern() + ")"
 }
 
-// automata generates a finite automaton for a zero-or-more repetition (Kleene closure) of the Pattern.
+// automata generates a finite automaton for a zero-or-more repetition (Kleene closure) of the Pattern,
+// enclosed in new start and final states for the same reason as for zeroOrOne.
 //
-//	    ______________
-//	   ^              \
-//	  /                v
-//	start --> ... --> final
-//	  ^                /
-//	   \              v
-//	    --------------
+//	    ______________________________
+//	   /                              \
+//	  /                                v
+//	start --> start' --> ... --> final' --> final
+//	            ^                  /
+//	             \                v
+//	              ----------------
 func (r *zeroOrMore) nfa() *automata {
+	a := automata{
+		Trans: make(transitions),
+		start: &stateObj{},
+		final: []state{&stateObj{}},
+	}
 	re := r.re.nfa()
-	re.addTransitions(re.start, map[char]state{&empty{}: re.final[0]})
-	re.addTransitions(re.final[0], map[char]state{&empty{}: re.start})
-	return re
+	a.merge(re)
+	a.addTransitions(a.start, map[char]state{epsilon(): re.start})
+	a.addTransitions(a.start, map[char]state{epsilon(): a.final[0]})
+	a.addTransitions(re.final[0], map[char]state{epsilon(): re.start})
+	a.addTransitions(re.final[0], map[char]state{epsilon(): a.final[0]})
+	return &a
 }
 
 func (r *oneOrMore) String() string {
-	return r.re.String() + "+"
+	return operand(r.re, 3) + "+"
 	//return "+(" + r.re.Pattern() + ")"
 }
 
@@ -258,86 +525,135 @@ func (r *oneOrMore) String() string {
 //	    ---------------
 func (r *oneOrMore) nfa() *automata {
 	re := r.re.nfa()
-	re.addTransitions(re.final[0], map[char]state{&empty{}: re.start})
+	re.addTransitions(re.final[0], map[char]state{epsilon(): re.start})
 	return re
 }
 
+// String returns the repetition with the shortest operator for its counts.
 func (r *repeat) String() string {
-	s := r.re.String() + "{"
-	if r.min == r.max {
-		s += strconv.Itoa(int(r.min))
-	} else {
-		if r.min != 0 {
-			s += strconv.Itoa(int(r.min))
-		}
-		s += ","
-		if r.max != math.MaxUint8 {
-			s += strconv.Itoa(int(r.max))
-		}
+	s := operand(r.re, 3)
+	switch {
+	case r.unbounded && r.min == 0:
+		return s + "*"
+	case r.unbounded && r.min == 1:
+		return s + "+"
+	case r.unbounded:
+		return s + "{" + strconv.Itoa(r.min) + ",}"
+	case r.min == 0 && r.max == 1:
+		return s + "?"
+	case r.min == r.max:
+		return s + "{" + strconv.Itoa(r.min) + "}"
+	default:
+		return s + "{" + strconv.Itoa(r.min) + "," + strconv.Itoa(r.max) + "}"
 	}
-	return s + "}"
 }
 
 // automata generates a finite automaton for a range (m,n) repetition of the Pattern.
+// The optional copies of the pattern after the first m can all be skipped directly
+// to the final state, so that the closure of a state does not go through the skips
+// of all the following copies, which would make the DFA construction quadratic.
 //
-//	                              ___________________
-//							     /   _______________ \
-//		    			  	    /   /           ___ \ \
-//	         +-m times--+      /   /           /   \ \ \
-//	         |          |     ^   ^           ^     v v v
-//	start -> r -> ...-> r -> r -> r -> ... -> r ->  final
+//	                           ____________________________
+//	                          /       ____________________ \
+//	                         /       /             ______ \ \
+//	         +-m times--+   /       /             /      v v v
+//	start -> r -> ...-> r -> r -> r -> ... -> r -> ----> final
 //	                         |                |
 //	                         +---n-m times----+
+//
+// For an unbounded repetition (m,), a single copy with a loop follows the first m copies.
 func (r *repeat) nfa() *automata {
 	a := &automata{
 		Trans: make(transitions),
 		start: &stateObj{},
 		final: []state{&stateObj{}},
 	}
-	first := true
-	if r.min > 0 {
-		s := &sequence{slices.Repeat([]Pattern{r.re}, int(r.min))}
-		a = s.nfa()
-		first = false
+	last := a.start
+	for i := 0; i < r.min; i++ {
+		re := r.re.nfa()
+		a.merge(re)
+		a.addTransitions(last, map[char]state{epsilon(): re.start})
+		last = re.final[0]
 	}
-	if r.max > r.min {
-		if r.max == 255 {
+	if r.unbounded {
+		re := r.re.nfa()
+		a.merge(re)
+		a.addTransitions(last, map[char]state{epsilon(): re.start})
+		a.addTransitions(last, map[char]state{epsilon(): a.final[0]})
+		a.addTransitions(re.final[0], map[char]state{epsilon(): re.start})
+		last = re.final[0]
+	} else {
+		for i := r.min; i < r.max; i++ {
 			re := r.re.nfa()
 			a.merge(re)
-			a.addTransitions(re.start, map[char]state{&empty{}: re.final[0]})
-			a.addTransitions(re.final[0], map[char]state{&empty{}: re.start})
-			if first {
-				a.start = re.start
-				first = false
-			} else {
-				a.addTransitions(a.final[0], map[char]state{&empty{}: re.start})
-			}
-			a.final = re.final
-		} else {
-			for i := r.min; i < r.max; i++ {
-				re := r.re.nfa()
-				a.merge(re)
-				a.addTransitions(re.start, map[char]state{&empty{}: re.final[0]})
-				if first {
-					a.start = re.start
-					first = false
-				} else {
-					a.addTransitions(a.final[0], map[char]state{&empty{}: re.start})
-				}
-				a.final = re.final
-			}
+			a.addTransitions(last, map[char]state{epsilon(): re.start})
+			a.addTransitions(last, map[char]state{epsilon(): a.final[0]})
+			last = re.final[0]
 		}
 	}
-	if first {
-		a.final[0] = a.start
-	}
+	a.addTransitions(last, map[char]state{epsilon(): a.final[0]})
 	return a
 }
 
+// maxRepeat is the largest repetition count, and maxStates the largest number of
+// states of the NFA of a repetition. Repetitions are expanded into copies of the NFA
+// of the repeated expression, so that the size of the automata grows with the counts:
+// large counts are checked at compile time instead of exhausting time and memory.
+const (
+	maxRepeat = 100_000
+	maxStates = 100_000
+)
+
+// nfaSize returns the number of states of the NFA of the pattern.
+func nfaSize(p Pattern) int {
+	switch p := p.(type) {
+	case *choice:
+		return nfaSize(p.left) + nfaSize(p.right) + 2
+	case *sequence:
+		size := 1
+		for _, e := range p.sequence {
+			size += nfaSize(e)
+		}
+		return size
+	case *zeroOrOne:
+		return nfaSize(p.opt) + 2
+	case *zeroOrMore:
+		return nfaSize(p.re) + 2
+	case *oneOrMore:
+		return nfaSize(p.re) + 2
+	case *repeat:
+		n := max(p.min, p.max, 1)
+		if p.unbounded {
+			n = p.min + 1
+		}
+		return nfaSize(p.re)*n + 2
+	case *captureGroup:
+		return nfaSize(p.re) + 2
+	default:
+		return 2
+	}
+}
+
 func (r *captureGroup) String() string {
+	if r.name != "" {
+		return "(?<" + r.name + ">" + r.re.String() + ")"
+	}
 	return "(" + r.re.String() + ")"
 }
 
+// automata constructs the NFA of the group enclosed between the tags recording
+// the start and the end of the group.
+//
+//	start --open--> ... --close--> final
 func (r *captureGroup) nfa() *automata {
-	return r.re.nfa()
+	a := automata{
+		Trans: make(transitions),
+		start: &stateObj{},
+		final: []state{&stateObj{}},
+	}
+	re := r.re.nfa()
+	a.merge(re)
+	a.addTransitions(a.start, map[char]state{&tag{order.Add(1), r.group, r.name, true}: re.start})
+	a.addTransitions(re.final[0], map[char]state{&tag{order.Add(1), r.group, r.name, false}: a.final[0]})
+	return &a
 }
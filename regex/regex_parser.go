@@ -6,11 +6,21 @@
 //	    | re ('*' | '+' | '?')
 //	    | re re
 //	    | '(' re ')'
+//	    | '(?P<' name '>' re ')'
+//	    | '(?<' name '>' re ')'
+//	    | '(?' flags ')'
+//	    | '(?' flags ':' re ')'
 //	    | ch
 //
-//	ch -> '[' '^'? (c ['-' c])+ ']'
+//	flags -> [imsux]* ['-' [imsux]*]
+//
+//	ch -> '[' '^'? (c ['-' c] | '[:' '^'? name ':]' | ('\p' | '\P') class)+ ']'
+//	    | ('\p' | '\P') class
 //	    | c
-//	    | '\' ('*' | '+' | '?' | '|' | '(' | ')' | '[' | ']')
+//	    | '^' | '$' | '\b' | '\B'
+//	    | '\' ('*' | '+' | '?' | '|' | '(' | ')' | '[' | ']' | '{' | '}' | '.' | '^' | '$' | '#' | ...)
+//	    | '\' ('n' | 't' | 'r' | 'f' | 'v' | '0')
+//	    | '\x' hex hex | '\x{' hex+ '}' | '\u' hex hex hex hex
 //
 //	Refactored to remove left-recursion and ambiguity:
 //	using: A = Aa|B  =>  A  = BA'
@@ -20,16 +30,21 @@
 //	term   = { factor }
 //	factor = base [('*' | '+' | '?')]
 //	base   = '(' regex ')'
+//	       | '(?' ['P'] '<' name '>' regex ')'
+//	       | '(?' flags [':' regex] ')'
 //	       | ch
+//
+// In free-spacing mode (?x), whitespace and comments from '#' to the end of the line
+// are ignored between the elements of regex, term and factor.
 
 package regex
 
 import (
 	"container/list"
-	"math"
 	"strconv"
 	"strings"
-	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ----------------Regex top-down parsing----------------//
@@ -37,13 +52,38 @@ type (
 	parser struct {
 		input    []rune
 		position int
-		group    *int
-		groups   *list.List
+		groups   *int
+
+		// strict parsing records the first syntax error in err, while lenient parsing
+		// ignores errors and interprets the pattern as best as it can.
+		strict bool
+		err    *SyntaxError
+
+		// flags are the modifiers set by the last (?flags) parsed, which apply to
+		// the rest of the enclosing group.
+		flags *modifier
 	}
 
+	// SyntaxError is returned when compiling a pattern which is not a valid regular expression.
+	SyntaxError struct {
+		// Pattern is the pattern compiled.
+		Pattern string
+
+		// Offset is the byte offset in the pattern where the error was found.
+		Offset int
+
+		// Expected is the construct expected at the offset.
+		Expected string
+	}
+
+	// modifier holds the modes set by modifiers, which apply to the characters
+	// and assertions parsed while they are in effect.
 	modifier struct {
-		caseInsensitive bool
-		unicode         bool
+		caseInsensitive bool // i: case-insensitive matching
+		unicode         bool // u: generation of Unicode characters
+		dotAll          bool // s: . matches newline
+		multiline       bool // m: ^ and $ match at the start and end of lines
+		extended        bool // x: free-spacing mode, ignoring whitespace and # comments
 	}
 )
 
@@ -55,6 +95,27 @@ func (r *parser) peek() rune {
 	}
 }
 
+// count returns the value of a repetition count, or more than maxRepeat if it is too
+// large. It returns false if the count is empty or invalid.
+func count(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil && n <= maxRepeat {
+		return n, true
+	}
+	return maxRepeat + 1, true
+}
+
+// peekAt returns the character at offset i from the current position, or 0 past the end.
+func (r *parser) peekAt(i int) rune {
+	if r.position+i < len(r.input) {
+		return r.input[r.position+i]
+	}
+	return 0
+}
+
 func (r *parser) next() rune {
 	c := r.input[r.position]
 	r.position++
@@ -65,8 +126,42 @@ func (r *parser) hasMore() bool {
 	return len(r.input) > r.position
 }
 
+// expect consumes the next character, recording a syntax error if it is not c.
+// Lenient parsing consumes any character instead, reading to the end of the pattern
+// when c is missing.
+func (r *parser) expect(c rune) {
+	if !r.hasMore() || r.peek() != c {
+		r.fail("'" + string(c) + "'")
+	}
+	if r.hasMore() {
+		r.next()
+	}
+}
+
+// fail records a syntax error at the current position if parsing is strict.
+func (r *parser) fail(expected string) {
+	r.failAt(r.position, expected)
+}
+
+// failAt records a syntax error at the position in the input if parsing is strict.
+// Only the first error is kept.
+func (r *parser) failAt(position int, expected string) {
+	if r.strict && r.err == nil {
+		r.err = &SyntaxError{string(r.input), len(string(r.input[:position])), expected}
+	}
+}
+
+// Error returns the description of the syntax error, with the pattern and a caret
+// pointing at the position of the error.
+func (e *SyntaxError) Error() string {
+	column := utf8.RuneCountInString(e.Pattern[:e.Offset])
+	return "regex: expected " + e.Expected + " at offset " + strconv.Itoa(e.Offset) + "\n" +
+		"\t" + e.Pattern + "\n" +
+		"\t" + strings.Repeat(" ", column) + "^"
+}
+
 func (r *parser) regex(mod *modifier) Pattern {
-	term := r.term(mod)
+	term, mod := r.term(mod)
 	if r.hasMore() && r.peek() == '|' {
 		r.next()
 		right := r.regex(mod)
@@ -76,19 +171,42 @@ func (r *parser) regex(mod *modifier) Pattern {
 	}
 }
 
-func (r *parser) term(mod *modifier) Pattern {
+// term parses a sequence of factors. It returns the sequence with the modifiers in
+// effect at its end, as (?flags) changes them up to the end of the enclosing group.
+func (r *parser) term(mod *modifier) (Pattern, *modifier) {
 	var factors []Pattern
-	for r.hasMore() && r.peek() != ')' && r.peek() != '|' {
+	for r.skip(mod); r.hasMore() && r.peek() != ')' && r.peek() != '|'; r.skip(mod) {
 		f := r.factor(mod)
+		if r.flags != nil {
+			mod, r.flags = r.flags, nil
+		}
 		if f != nil {
 			factors = append(factors, f)
 		}
 	}
-	return &sequence{factors}
+	return &sequence{factors}, mod
+}
+
+// skip skips whitespace and comments, from '#' to the end of the line, in free-spacing mode.
+func (r *parser) skip(mod *modifier) {
+	for mod.extended && r.hasMore() {
+		if c := r.peek(); unicode.IsSpace(c) {
+			r.next()
+		} else if c == '#' {
+			for r.hasMore() && r.next() != '\n' {
+			}
+		} else {
+			return
+		}
+	}
 }
 
 func (r *parser) factor(mod *modifier) Pattern {
 	base := r.base(mod)
+	if base == nil {
+		return nil
+	}
+	r.skip(mod)
 	if r.hasMore() {
 		switch r.peek() {
 		case '*':
@@ -101,56 +219,68 @@ func (r *parser) factor(mod *modifier) Pattern {
 			r.next()
 			return &zeroOrOne{base}
 		case '{':
+			start := r.position
 			r.next()
 			m := ""
 			n := ""
 			first := true
 			if r.hasMore() {
+				closed := false
 				for r.hasMore() {
 					c := r.next()
 					if c == '}' {
+						closed = true
 						break
 					}
 					if c == ',' {
+						if !first {
+							r.failAt(r.position-1, "'}'")
+						}
 						first = false
-					} else if first {
-						m += string(c)
 					} else {
-						n += string(c)
+						if c < '0' || c > '9' {
+							r.failAt(r.position-1, "repetition count")
+						}
+						if first {
+							m += string(c)
+						} else {
+							n += string(c)
+						}
 					}
 				}
-				var mi, ma int32
-				if len(strings.TrimSpace(m)) == 0 {
-					mi = 0
-				} else {
-					x, err := strconv.Atoi(m)
-					if err != nil {
-						mi = 0
-					} else {
-						mi = int32(x)
-					}
+				if !closed {
+					r.fail("'}'")
 				}
-				if len(strings.TrimSpace(n)) == 0 {
-					ma = math.MaxUint8
-				} else {
-					x, err := strconv.Atoi(n)
-					if err != nil {
-						ma = math.MaxUint8
-					} else {
-						ma = int32(x)
-					}
+				mi, ma, unbounded := 0, 0, false
+				// lenient parsing: an invalid minimum is 0 and an invalid maximum is unbounded
+				if c, ok := count(m); ok {
+					mi = c
 				}
 				if first {
 					ma = mi
+				} else if c, ok := count(n); ok {
+					ma = c
+				} else {
+					unbounded = true
+				}
+				if mi > maxRepeat || ma > maxRepeat {
+					r.failAt(start, "repetition count not greater than "+strconv.Itoa(maxRepeat))
+					mi, ma = min(mi, maxRepeat), min(ma, maxRepeat)
+				} else if !unbounded && mi > ma {
+					r.failAt(start, "minimum repetition count not greater than maximum")
+				}
+				if !unbounded && mi > ma {
+					mi, ma = ma, mi
 				}
-				mi = min(math.MaxUint8, max(0, mi))
-				ma = min(math.MaxUint8, max(0, ma))
-				if mi > ma {
-					ma = atomic.SwapInt32(&mi, ma)
+				if size := nfaSize(base); size*max(mi, ma, 1) > maxStates {
+					// lenient parsing reduces the counts to the largest possible
+					r.failAt(start, "repetition with an automaton of at most "+strconv.Itoa(maxStates)+" states")
+					mi, ma = min(mi, maxStates/size), min(ma, maxStates/size)
 				}
-				return &repeat{base, uint8(mi), uint8(ma)}
+				return &repeat{base, mi, ma, unbounded}
 			} else {
-				return &singleChar{mod, '{', cp(r.groups)}
+				r.fail("repetition count")
+				return &singleChar{mod, '{'}
 			}
 		}
 	}
@@ -161,30 +291,83 @@ func (r *parser) base(mod *modifier) Pattern {
 	if r.peek() == '(' {
 		r.next()
 		if r.peek() == '?' {
-			// modifiers
 			r.next()
-			if r.hasMore() {
-				switch r.next() {
+			if r.peek() == '<' || (r.peek() == 'P' && r.peekAt(1) == '<') {
+				// named group
+				if r.peek() == 'P' {
+					r.next()
+				}
+				r.next()
+				var name strings.Builder
+				for r.hasMore() && r.peek() != '>' {
+					c := r.next()
+					if !wordSpans.match(c) {
+						r.failAt(r.position-1, "group name")
+					}
+					name.WriteRune(c)
+				}
+				if name.Len() == 0 {
+					r.fail("group name")
+				}
+				r.expect('>')
+				return r.group(mod, name.String())
+			}
+
+			// modifiers, which are scoped to the group in (?flags:re), or apply to the
+			// rest of the enclosing group in (?flags)
+			flags := *mod
+			negate, empty := false, true
+			for r.hasMore() && r.peek() != ')' && r.peek() != ':' {
+				c := r.next()
+				switch c {
+				case '-':
+					if negate {
+						r.failAt(r.position-1, "modifier")
+					}
+					negate, empty = true, true
+					continue
 				case 'i':
-					mod.caseInsensitive = true
+					flags.caseInsensitive = !negate
 				case 'u':
-					mod.unicode = true
+					flags.unicode = !negate
+				case 's':
+					flags.dotAll = !negate
+				case 'm':
+					flags.multiline = !negate
+				case 'x':
+					flags.extended = !negate
+				default:
+					r.failAt(r.position-1, "modifier")
 				}
+				empty = false
 			}
-
-			// lenient parsing: don't break if no closing bracket, read to the end
-			if r.hasMore() {
+			if r.peek() == ':' {
+				// non-capturing group
 				r.next()
+				re := r.regex(&flags)
+				r.expect(')')
+				return re
+			}
+			if empty {
+				r.fail("modifier")
 			}
+
+			// lenient parsing: don't break if no closing bracket, read to the end
+			r.expect(')')
+			r.flags = &flags
 			return nil
 		} else if r.peek() == ':' {
 			// list
 			r.next()
 			var conversion conversion
 			var listName strings.Builder
+			start := r.position
 			for r.hasMore() && r.peek() != ')' && r.peek() != ':' {
 				listName.WriteRune(r.next())
 			}
+			if listName.Len() == 0 {
+				r.fail("list name")
+			}
 			if r.peek() == ':' {
 				r.next()
 				for r.hasMore() && r.peek() != ')' {
@@ -199,11 +382,14 @@ func (r *parser) base(mod *modifier) Pattern {
 						conversion.singleSpace = true
 					case 'm':
 						conversion.trim = true
+					default:
+						r.failAt(r.position-1, "list conversion (l, u, t, s or m)")
 					}
 				}
 			}
-			if r.hasMore() {
-				r.next()
+			r.expect(')')
+			if _, ok := lookupList(listName.String()); listName.Len() > 0 && !ok {
+				r.failAt(start, "registered word list")
 			}
 			return &inList{
 				mod:     mod,
@@ -211,23 +397,25 @@ func (r *parser) base(mod *modifier) Pattern {
 				convert: conversion,
 			}
 		} else {
-			*r.group++
-			r.groups.PushBack(*r.group)
-
-			re := r.regex(mod)
-			r.groups.Remove(r.groups.Back())
-
-			// lenient parsing: don't break if no closing bracket, read to the end
-			if r.hasMore() {
-				r.next()
-			}
-			return &captureGroup{re}
+			return r.group(mod, "")
 		}
 	} else {
 		return r.ch(mod)
 	}
 }
 
+// group parses the regex of a capturing group with the given name (empty if unnamed),
+// following the opening bracket, and numbers the group in order of its opening bracket.
+func (r *parser) group(mod *modifier, name string) Pattern {
+	*r.groups++
+	group := *r.groups
+	re := r.regex(mod)
+
+	// lenient parsing: don't break if no closing bracket, read to the end
+	r.expect(')')
+	return &captureGroup{re, group, name}
+}
+
 func (r *parser) ch(mod *modifier) Pattern {
 	if r.peek() == '[' {
 		r.next()
@@ -240,84 +428,263 @@ func (r *parser) ch(mod *modifier) Pattern {
 
 		charSets := list.New()
 		for r.hasMore() && r.peek() != ']' {
-			from := r.next()
+			if r.peek() == '[' && r.peekAt(1) == ':' {
+				for _, c := range r.posixClass(mod) {
+					charSets.PushBack(c)
+				}
+				continue
+			} else if r.peek() == '\\' && (r.peekAt(1) == 'p' || r.peekAt(1) == 'P') {
+				r.next()
+				for _, c := range r.unicodeClass(mod, r.next() == 'P') {
+					charSets.PushBack(c)
+				}
+				continue
+			} else if r.peek() == '\\' && strings.ContainsRune("dDsSwW", r.peekAt(1)) {
+				r.next()
+				charSets.PushBack(r.classEscape(mod, r.next()))
+				continue
+			}
+			start := r.position
+			from := r.setChar()
 			if r.peek() == '-' {
 				r.next()
 				if r.hasMore() && r.peek() != ']' {
-					to := r.next()
-					charSets.PushBack(&charRange{mod, from, to, cp(r.groups)})
+					to := r.setChar()
+					if to < from {
+						r.failAt(start, "range in increasing order")
+					}
+					charSets.PushBack(&charRange{mod, from, to})
 				} else {
-					charSets.PushBack(&charRange{mod, from, math.MaxUint8, cp(r.groups)})
+					// a '-' at the end of the set is literal, as at its start
+					charSets.PushBack(&singleChar{mod, from})
+					charSets.PushBack(&singleChar{mod, '-'})
 				}
 			} else {
-				charSets.PushBack(&singleChar{mod, from, cp(r.groups)})
+				charSets.PushBack(&singleChar{mod, from})
 			}
 		}
 		// lenient parsing: don't break if no closing square bracket, read to the end
-		if r.hasMore() {
-			r.next()
-		}
-		return &charSet{mod, exclude, *charSets, cp(r.groups), nil}
+		r.expect(']')
+		return &charSet{mod, exclude, *charSets, nil}
 
 	} else if r.peek() == '\\' {
 		r.next()
 		// lenient parsing: a single backlash at the end is interpreted as escaping itself
 		if r.hasMore() {
-			switch c := r.next(); c {
-			case 'd':
-				return &charRange{mod, '0', '9', cp(r.groups)}
-			case 'D':
-				cs := list.New()
-				cs.PushBack(&charRange{mod, '0', '9', cp(r.groups)})
-				return &charSet{mod, true, *cs, cp(r.groups), nil}
-			case 's':
-				cs := list.New()
-				cs.PushBack(&singleChar{mod, ' ', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\t', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\n', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\f', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\r', cp(r.groups)})
-				return &charSet{mod, false, *cs, cp(r.groups), nil}
-			case 'S':
-				cs := list.New()
-				cs.PushBack(&singleChar{mod, ' ', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\t', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\n', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\f', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '\r', cp(r.groups)})
-				return &charSet{mod, true, *cs, cp(r.groups), nil}
-			case 'w':
-				cs := list.New()
-				cs.PushBack(&charRange{mod, '0', '9', cp(r.groups)})
-				cs.PushBack(&charRange{mod, 'a', 'z', cp(r.groups)})
-				cs.PushBack(&charRange{mod, 'A', 'Z', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '_', cp(r.groups)})
-				return &charSet{mod, false, *cs, cp(r.groups), nil}
-			case 'W':
-				cs := list.New()
-				cs.PushBack(&charRange{mod, '0', '9', cp(r.groups)})
-				cs.PushBack(&charRange{mod, 'a', 'z', cp(r.groups)})
-				cs.PushBack(&charRange{mod, 'A', 'Z', cp(r.groups)})
-				cs.PushBack(&singleChar{mod, '_', cp(r.groups)})
-				return &charSet{mod, true, *cs, cp(r.groups), nil}
+			switch c := r.peek(); c {
+			case 'd', 'D', 's', 'S', 'w', 'W':
+				r.next()
+				return r.classEscape(mod, c)
+			case 'p', 'P':
+				r.next()
+				chars := r.unicodeClass(mod, c == 'P')
+				if len(chars) == 1 {
+					return chars[0]
+				}
+				seq := make([]Pattern, len(chars))
+				for i, c := range chars {
+					seq[i] = c
+				}
+				return &sequence{seq}
+			case 'b':
+				r.next()
+				return &assertion{mod, WordBoundary}
+			case 'B':
+				r.next()
+				return &assertion{mod, NonWordBoundary}
 			default:
-				return &singleChar{mod, c, cp(r.groups)}
+				return &singleChar{mod, r.escapedChar()}
 			}
 		} else {
-			return &singleChar{mod, '\\', cp(r.groups)}
+			r.fail("escaped character")
+			return &singleChar{mod, '\\'}
 		}
 	} else if r.peek() == '.' {
 		r.next()
 		return &anyChar{mod: mod}
+	} else if r.peek() == '^' {
+		r.next()
+		return &assertion{mod, TextStart}
+	} else if r.peek() == '$' {
+		r.next()
+		return &assertion{mod, TextEnd}
 	} else {
-		return &singleChar{mod, r.next(), cp(r.groups)}
+		if c := r.peek(); c == '*' || c == '+' || c == '?' {
+			r.fail("expression to repeat before '" + string(c) + "'")
+		}
+		return &singleChar{mod, r.next()}
 	}
 }
 
-func cp(groups *list.List) list.List {
-	cp := list.New()
-	for g := groups.Front(); g != nil; g = g.Next() {
-		cp.PushBack(g.Value)
+// unicodeClass parses the name of a Unicode class following \p or \P, either a single
+// letter or a name in braces which is negated when it starts with ^, as in \p{^Greek}.
+// Lenient parsing of an unknown class returns its text as single characters.
+func (r *parser) unicodeClass(mod *modifier, exclude bool) []char {
+	start := r.position - 2
+	var name strings.Builder
+	if r.peek() == '{' {
+		r.next()
+		for r.hasMore() && r.peek() != '}' {
+			name.WriteRune(r.next())
+		}
+		r.expect('}')
+	} else if r.hasMore() {
+		name.WriteRune(r.next())
+	}
+	n := name.String()
+	if len(n) > 1 && n[0] == '^' {
+		n = n[1:]
+		exclude = !exclude
+	}
+	if c := newClass(mod, n, exclude, false); c != nil {
+		return []char{c}
+	}
+	r.failAt(start, "Unicode class name")
+	return r.literal(mod, start+1)
+}
+
+// posixClass parses a POSIX class in a character set, such as [:alpha:] or [:^alpha:].
+// Lenient parsing of an unknown class returns its text as single characters.
+func (r *parser) posixClass(mod *modifier) []char {
+	start := r.position
+	r.next()
+	r.next()
+	exclude := false
+	if r.peek() == '^' {
+		r.next()
+		exclude = true
+	}
+	var name strings.Builder
+	for r.hasMore() && r.peek() != ':' && r.peek() != ']' {
+		name.WriteRune(r.next())
+	}
+	if r.peek() != ':' || r.peekAt(1) != ']' {
+		r.fail("':]'")
+		return r.literal(mod, start)
+	}
+	r.next()
+	r.next()
+	if c := newClass(mod, name.String(), exclude, true); c != nil {
+		return []char{c}
+	}
+	r.failAt(start, "POSIX class name")
+	return r.literal(mod, start)
+}
+
+// literal returns the characters of the input from start to the current position.
+func (r *parser) literal(mod *modifier, start int) []char {
+	var chars []char
+	for _, c := range r.input[start:r.position] {
+		chars = append(chars, &singleChar{mod, c})
+	}
+	return chars
+}
+
+// classEscape returns the class of characters for the escapes \d, \D, \s, \S, \w and \W.
+func (r *parser) classEscape(mod *modifier, c rune) char {
+	switch c {
+	case 'd':
+		return &charRange{mod, '0', '9'}
+	case 'D':
+		cs := list.New()
+		cs.PushBack(&charRange{mod, '0', '9'})
+		return &charSet{mod, true, *cs, nil}
+	case 's', 'S':
+		cs := list.New()
+		cs.PushBack(&singleChar{mod, ' '})
+		cs.PushBack(&singleChar{mod, '\t'})
+		cs.PushBack(&singleChar{mod, '\n'})
+		cs.PushBack(&singleChar{mod, '\f'})
+		cs.PushBack(&singleChar{mod, '\r'})
+		return &charSet{mod, c == 'S', *cs, nil}
+	default:
+		cs := list.New()
+		cs.PushBack(&charRange{mod, '0', '9'})
+		cs.PushBack(&charRange{mod, 'a', 'z'})
+		cs.PushBack(&charRange{mod, 'A', 'Z'})
+		cs.PushBack(&singleChar{mod, '_'})
+		return &charSet{mod, c == 'W', *cs, nil}
+	}
+}
+
+// setChar returns the next character in a character set, which may be escaped.
+func (r *parser) setChar() rune {
+	if r.peek() == '\\' {
+		r.next()
+		return r.escapedChar()
+	}
+	return r.next()
+}
+
+// escapedChar returns the character escaped by the backslash preceding the current
+// position: a control character (\n, \t, \r, \f, \v and \0 for the null character), a
+// code point in hexadecimal (\xHH, \x{H...} and \uHHHH), or the character itself.
+func (r *parser) escapedChar() rune {
+	if !r.hasMore() {
+		// lenient parsing: a single backlash at the end is interpreted as escaping itself
+		r.fail("escaped character")
+		return '\\'
+	}
+	switch c := r.next(); c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	case '0':
+		return 0
+	case 'x':
+		if r.peek() == '{' {
+			r.next()
+			cp, ok := r.hex(1, 6)
+			if ok && cp > utf8.MaxRune {
+				r.failAt(r.position-1, "code point not greater than 10FFFF")
+			}
+			r.expect('}')
+			return cp
+		} else if cp, ok := r.hex(2, 2); ok {
+			return cp
+		}
+		return c
+	case 'u':
+		if cp, ok := r.hex(4, 4); ok {
+			return cp
+		}
+		return c
+	default:
+		return c
+	}
+}
+
+// hex reads between min and max hexadecimal digits, returning the value read. If fewer
+// than min digits are found, it returns false after going back to the first digit, so
+// that lenient parsing takes the escape literally.
+func (r *parser) hex(min, max int) (rune, bool) {
+	start := r.position
+	var cp rune
+	for r.position-start < max {
+		c := r.peek()
+		switch {
+		case '0' <= c && c <= '9':
+			cp = cp*16 + c - '0'
+		case 'a' <= c && c <= 'f':
+			cp = cp*16 + c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			cp = cp*16 + c - 'A' + 10
+		default:
+			if r.position-start < min {
+				r.fail("hexadecimal digit")
+				r.position = start
+				return 0, false
+			}
+			return cp, true
+		}
+		r.next()
 	}
-	return *cp
+	return cp, true
 }
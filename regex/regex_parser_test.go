@@ -0,0 +1,180 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	r, err := Compile("(a|b)+[0-9]{2,3}\\.")
+	if err != nil {
+		t.Fatal("valid pattern not compiled:", err)
+	}
+	if !r.Match("ab12.") {
+		t.Error("'(a|b)+[0-9]{2,3}\\.' did not match 'ab12.'")
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		offset   int
+		expected string
+	}{
+		{"(ab", 3, "')'"},
+		{"[a-z", 4, "']'"},
+		{"ab\\", 3, "escaped character"},
+		{"a{2,x}", 4, "repetition count"},
+		{"a{2,3", 5, "'}'"},
+		{"a{3,2}", 1, "minimum repetition count not greater than maximum"},
+		{"a{100001}", 1, "repetition count not greater than 100000"},
+		{"a{99999999999999999999}", 1, "repetition count not greater than 100000"},
+		{"(a{1000}){1000}", 9, "repetition with an automaton of at most 100000 states"},
+		{"ab)c", 2, "end of pattern"},
+		{"*a", 0, "expression to repeat before '*'"},
+		{"[z-a]", 1, "range in increasing order"},
+		{"[a-", 3, "']'"},
+		{"(?<>a)", 3, "group name"},
+		{"(?<na me>a)", 5, "group name"},
+		{"(?qa)", 2, "modifier"},
+		{"(?i", 3, "')'"},
+		{"(?ia", 3, "modifier"},
+		{"(?)", 2, "modifier"},
+		{"(?i--s)", 4, "modifier"},
+		{"(?i:a", 5, "')'"},
+		{"(:names:x)", 8, "list conversion (l, u, t, s or m)"},
+		{"(:names)", 2, "registered word list"},
+		{"é(", 3, "')'"},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.pattern)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a syntax error, got %v", test.pattern, err)
+			continue
+		}
+		if syntaxErr.Offset != test.offset || syntaxErr.Expected != test.expected {
+			t.Errorf("%q: expected %s at %d, got %s at %d", test.pattern,
+				test.expected, test.offset, syntaxErr.Expected, syntaxErr.Offset)
+		}
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	_, err := Compile("a(b|cé")
+	expected := "regex: expected ')' at offset 7\n\ta(b|cé\n\t      ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err)
+	}
+}
+
+func TestLenient(t *testing.T) {
+	for _, pattern := range []string{"(ab", "[a-z", "ab\\", "a{2,x}", "ab)c", "*a"} {
+		if _, err := Compile(pattern, Lenient()); err != nil {
+			t.Errorf("%q not compiled leniently: %v", pattern, err)
+		}
+	}
+	if r := NewRegex("(ab"); !r.Match("ab") {
+		t.Error("lenient '(ab' did not match 'ab'")
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(error).Error(), "expected ']'") {
+			t.Error("MustCompile did not panic with the syntax error:", r)
+		}
+	}()
+	MustCompile("[a")
+}
+
+func TestEscapes(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"a\\nb", "a\nb", true},
+		{"a\\nb", "anb", false},
+		{"\\t\\r\\f\\v\\0", "\t\r\f\v\x00", true},
+		{"\\x41\\x{1F600}\\u00e9", "A\U0001F600é", true},
+		{"[\\t\\n]+", "\t\n\t", true},
+		{"[\\x41-\\x43]+", "ABC", true},
+		{"[\\x41-\\x43]+", "ABD", false},
+		{"[a\\]]+", "a]a", true},
+		{"[a\\-z]+", "-az", true},
+		{"[a\\-z]+", "b", false},
+		{"[\\^a]+", "^a", true},
+		{"[\\\\]", "\\", true},
+		{"[\\d_]+", "1_2", true},
+		{"[\\w\\s]+", "a b", true},
+		{"[^\\d]", "5", false},
+	}
+	for _, test := range tests {
+		r, err := Compile(test.pattern)
+		if err != nil {
+			t.Error(err)
+		} else if r.Match(test.input) != test.match {
+			t.Errorf("%q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+	}
+	for _, pattern := range []string{"\\xZ1", "\\x{}", "\\x{110000}", "\\u12", "[\\x4]"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("%q compiled without error", pattern)
+		}
+	}
+	if !NewRegex("\\xZZ").Match("xZZ") {
+		t.Error("lenient '\\xZZ' did not match 'xZZ'")
+	}
+}
+
+func TestSetDashes(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{"[+-]", "+", true},
+		{"[+-]", "-", true},
+		{"[+-]", "z", false},
+		{"[a-]", "a", true},
+		{"[a-]", "-", true},
+		{"[a-]", "b", false},
+		{"[-a]", "-", true},
+		{"[-a]", "a", true},
+		{"[-a]", "b", false},
+		{"[^a-]", "-", false},
+		{"[^a-]", "b", true},
+	}
+	for _, test := range tests {
+		r, err := Compile(test.pattern)
+		if err != nil {
+			t.Error(err)
+		} else if r.Match(test.input) != test.match {
+			t.Errorf("%q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+		if NewRegex(test.pattern).Match(test.input) != test.match {
+			t.Errorf("lenient %q matching %q: expected %v", test.pattern, test.input, test.match)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	s := "a.b^c$d(e)[f]{g}|h*i+j?k\\l#m n-o"
+	r, err := Compile("^" + Escape(s) + "$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Match(s) {
+		t.Errorf("escaped %q did not match itself", s)
+	}
+	if r, err = Compile("(?x)" + Escape(s)); err != nil || !r.Match(s) {
+		t.Errorf("escaped %q did not match itself in free-spacing mode", s)
+	}
+	if r, err = Compile("[" + Escape("]^-\\") + "]+"); err != nil || !r.Match("]^-\\") {
+		t.Error("escaped set did not match its characters")
+	}
+}
@@ -36,6 +36,10 @@ func TestMultipleRange(t *testing.T) {
 
 		re.WriteRune(rune(i - 2))
 		re.WriteRune('-')
+		if i == '\\' {
+			// escaped in character sets
+			re.WriteRune('\\')
+		}
 		re.WriteRune(rune(i))
 
 		//s.WriteRune(rune(start + rand.Intn(end-start+1)))
@@ -91,25 +95,18 @@ func Test2(t *testing.T) {
 func TestCaptureGroup(t *testing.T) {
 	r := NewRegex("(aab)|(aac)")
 	m := r.Matcher()
-	println(r.Dfa.GraphViz("(aab)|(aac)"))
-
 	m.Match("aab")
-	for k, v := range m.Groups {
-		println(k, v)
+	groups := m.Groups()
+	if len(groups) != 3 || groups[1].Text != "aab" || groups[2].Start != -1 {
+		t.Error("'(aab)|(aac)' did not capture group 1 in 'aab':", groups)
 	}
 
 	m.Reset()
 	m.Match("aac")
-	for k, v := range m.Groups {
-		println(k, v)
+	groups = m.Groups()
+	if len(groups) != 3 || groups[1].Start != -1 || groups[2].Text != "aac" {
+		t.Error("'(aab)|(aac)' did not capture group 2 in 'aac':", groups)
 	}
-
-	//if !r.Match("") {
-	//	t.Error("'' did not match ''")
-	//}
-	//if r.Match("a") {
-	//	t.Error("'' matched 'a'")
-	//}
 }
 
 func TestEmpty(t *testing.T) {
@@ -353,6 +350,41 @@ func TestRepeatExact(t *testing.T) {
 	}
 }
 
+func TestRepeatLarge(t *testing.T) {
+	r := MustCompile("\\d{300}")
+	if !r.Match(strings.Repeat("7", 300)) {
+		t.Error("'\\d{300}' did not match 300 digits")
+	}
+	if r.Match(strings.Repeat("7", 299)) || r.Match(strings.Repeat("7", 301)) {
+		t.Error("'\\d{300}' matched 299 or 301 digits")
+	}
+	r = MustCompile("a{0,255}")
+	if !r.Match(strings.Repeat("a", 255)) {
+		t.Error("'a{0,255}' did not match 255 a's")
+	}
+	if r.Match(strings.Repeat("a", 256)) {
+		t.Error("'a{0,255}' matched 256 a's")
+	}
+	r = MustCompile("x[a-z]{2,1000}y")
+	if !r.Match("x" + strings.Repeat("q", 1000) + "y") {
+		t.Error("'x[a-z]{2,1000}y' did not match 1000 letters")
+	}
+	if r.Match("x" + strings.Repeat("q", 1001) + "y") {
+		t.Error("'x[a-z]{2,1000}y' matched 1001 letters")
+	}
+	if r.String() != "x[a-z]{2,1000}y" {
+		t.Error("unexpected pattern", r.String())
+	}
+}
+
+func TestRepeatGroup(t *testing.T) {
+	m := MustCompile("(a|b){2,4}").Matcher()
+	m.Match("abba")
+	if groups := m.Groups(); groups[1].Text != "a" || groups[1].Start != 3 {
+		t.Error("'(a|b){2,4}' did not capture the last iteration in 'abba':", groups)
+	}
+}
+
 func TestRepeatNoUpperLimit(t *testing.T) {
 	r := NewRegex("(ab|ac){3,}")
 	if !r.Match("abacab") {
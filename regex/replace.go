@@ -0,0 +1,105 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// ReplaceAll returns a copy of the input with all matches of the regular expression
+// replaced by the template, in which group references are expanded by Expand.
+func (r *Regex) ReplaceAll(input, template string) string {
+	return r.ReplaceAllFunc(input, func(f *Found) string {
+		return r.Expand(template, f)
+	})
+}
+
+// ReplaceAllFunc returns a copy of the input with every match of the regular expression
+// replaced by the text returned by the replace function for that match.
+func (r *Regex) ReplaceAllFunc(input string, replace func(f *Found) string) string {
+	var s strings.Builder
+	last := 0
+	for f := range r.FindAll(input) {
+		s.WriteString(input[last:f.Start])
+		s.WriteString(replace(f))
+		last = f.End
+	}
+	s.WriteString(input[last:])
+	return s.String()
+}
+
+// ReplaceAllGenerate returns a copy of the input with every match of the regular
+// expression replaced by a random string generated from the generator regular
+// expression. When the generator has the same structure as the data matched, this
+// anonymizes the data while preserving its format.
+func (r *Regex) ReplaceAllGenerate(input string, generator *Regex) string {
+	return r.ReplaceAllGenerateWith(input, generator, nil)
+}
+
+// ReplaceAllGenerateWith is ReplaceAllGenerate with the random number generator used
+// by the generator regular expression (see GenerateWith), for reproducible replacements.
+func (r *Regex) ReplaceAllGenerateWith(input string, generator *Regex, rng *rand.Rand) string {
+	return r.ReplaceAllFunc(input, func(*Found) string {
+		return generator.GenerateWith(rng)
+	})
+}
+
+// Expand returns the template with references to the groups of the match replaced
+// by the texts captured by the groups: $n and ${n} are replaced by the text of group
+// n, and ${name} by the text of the group with that name. $$ is replaced by a single
+// $. References to groups which do not exist or did not capture anything are replaced
+// by an empty string.
+func (r *Regex) Expand(template string, f *Found) string {
+	var s strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '$' || i == len(template)-1 {
+			s.WriteByte(c)
+			continue
+		}
+		i++
+		switch {
+		case template[i] == '$':
+			s.WriteByte('$')
+		case template[i] == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end == -1 {
+				// not a reference: copy the rest of the template as is
+				s.WriteString(template[i-1:])
+				return s.String()
+			}
+			s.WriteString(f.group(r.group(template[i+1 : i+end])))
+			i += end
+		case '0' <= template[i] && template[i] <= '9':
+			start := i
+			for i+1 < len(template) && '0' <= template[i+1] && template[i+1] <= '9' {
+				i++
+			}
+			s.WriteString(f.group(r.group(template[start : i+1])))
+		default:
+			s.WriteByte('$')
+			s.WriteByte(template[i])
+		}
+	}
+	return s.String()
+}
+
+// group returns the number of the group referred to by ref, either by number or by
+// name, or -1 if there is none.
+func (r *Regex) group(ref string) int {
+	if n, err := strconv.Atoi(ref); err == nil {
+		return n
+	}
+	return r.SubexpIndex(ref)
+}
+
+// group returns the text captured by group g in the match, or an empty string if
+// there is no such group.
+func (f *Found) group(g int) string {
+	if g < 0 || g >= len(f.Groups) {
+		return ""
+	}
+	return f.Groups[g].Text
+}
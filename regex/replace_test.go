@@ -0,0 +1,54 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplaceAll(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		input    string
+		template string
+		expected string
+	}{
+		{"(\\d{4})-(\\d{2})", "from 2024-05 to 2025-11.", "$2/$1", "from 05/2024 to 11/2025."},
+		{"(\\w+)@(\\w+)", "mail bob@site now", "${1}_at_${2}", "mail bob_at_site now"},
+		{"\\d+", "a1b22", "<$0>", "a<1>b<22>"},
+		{"x", "axbx", "$$", "a$b$"},
+		{"x", "ax", "$9${9}", "a"},
+		{"x", "ax", "${1", "a${1"},
+		{"y", "ax", "z", "ax"},
+		{"(?<user>\\w+)@(?P<host>\\w+)", "mail bob@site now", "${host}:${user}", "mail site:bob now"},
+		{"(?<user>\\w+)", "bob", "${nobody}", ""},
+	}
+	for _, test := range tests {
+		actual := NewRegex(test.pattern).ReplaceAll(test.input, test.template)
+		if actual != test.expected {
+			t.Errorf("%q in %q with %q: expected %q, got %q", test.pattern, test.input, test.template, test.expected, actual)
+		}
+	}
+}
+
+func TestReplaceAllFunc(t *testing.T) {
+	r := NewRegex("[a-z]+")
+	actual := r.ReplaceAllFunc("ab 12 cd", func(f *Found) string {
+		return strings.ToUpper(f.Text)
+	})
+	if actual != "AB 12 CD" {
+		t.Error("expected 'AB 12 CD', got", actual)
+	}
+}
+
+func TestReplaceAllGenerate(t *testing.T) {
+	r := NewRegex("\\d{3}-\\d{4}")
+	input := "call 555-1234 or 555-9876 now"
+	for i := 0; i < 100; i++ {
+		actual := r.ReplaceAllGenerate(input, r)
+		if !NewRegex("call \\d{3}-\\d{4} or \\d{3}-\\d{4} now").Match(actual) {
+			t.Error("anonymized text does not preserve the format:", actual)
+		}
+	}
+}
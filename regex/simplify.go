@@ -0,0 +1,330 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Patterns are simplified bottom-up: the operands of a node are simplified before
+// the node itself, which is then rewritten into a simpler form matching the same
+// strings. Sequences and choices are flattened, characters and character sets in
+// a choice are merged into a single set, alternatives starting with the same
+// pattern are factored, and repetitions are combined (x x* becomes x+, (x*)? and
+// (x+)* become x*, x{0,1} becomes x?, etc.).
+//
+// Patterns containing capturing groups are not duplicated, removed or factored, so
+// that the groups of the simplified pattern are the same as those of the original.
+// The order of alternatives can change, however, which can change the groups captured
+// by a match.
+
+// Simplify returns a simpler pattern matching the same strings as p.
+func Simplify(p Pattern) Pattern {
+	switch p := p.(type) {
+	case *choice:
+		return simplifyChoice(alternatives(p))
+	case *sequence:
+		var seq []Pattern
+		for _, e := range p.sequence {
+			for _, s := range elements(Simplify(e)) {
+				seq = fold(seq, s)
+			}
+		}
+		return cat(seq...)
+	case *zeroOrOne:
+		return optional(Simplify(p.opt))
+	case *zeroOrMore:
+		return star(Simplify(p.re))
+	case *oneOrMore:
+		return plus(Simplify(p.re))
+	case *repeat:
+		re := Simplify(p.re)
+		switch {
+		case p.unbounded && p.min == 0:
+			return star(re)
+		case p.unbounded && p.min == 1:
+			return plus(re)
+		case p.unbounded:
+			return &repeat{re, p.min, p.max, true}
+		case p.max == 0 && !hasGroup(re):
+			return &sequence{}
+		case p.min == 0 && p.max == 1:
+			return optional(re)
+		case p.min == 1 && p.max == 1:
+			return re
+		}
+		return &repeat{re, p.min, p.max, false}
+	case *captureGroup:
+		return &captureGroup{Simplify(p.re), p.group, p.name}
+	}
+	return p
+}
+
+// simplifyChoice returns the simplified choice between the alternatives.
+func simplifyChoice(alts []Pattern) Pattern {
+	var simplified []Pattern
+	seen := set[string]{}
+	matchEmpty := false
+	for _, a := range alts {
+		for _, s := range alternatives(Simplify(a)) {
+			if isEpsilon(s) {
+				matchEmpty = true
+			} else if !seen[s.String()] || hasGroup(s) {
+				seen[s.String()] = true
+				simplified = append(simplified, s)
+			}
+		}
+	}
+	p := choiceOf(mergeClasses(factor(simplified)))
+	if matchEmpty {
+		if p == nil {
+			return &sequence{}
+		}
+		return optional(p)
+	}
+	return p
+}
+
+// factor combines the alternatives starting with the same pattern, without groups,
+// into a single alternative: the common pattern followed by the choice between the
+// rest of the alternatives. Alternatives are kept in the order of their first one.
+func factor(alts []Pattern) []Pattern {
+	type bucket struct {
+		head Pattern
+		rest []Pattern
+		alt  Pattern
+	}
+	var buckets []*bucket
+	heads := map[string]*bucket{}
+	for _, a := range alts {
+		elems := elements(a)
+		if len(elems) == 0 || hasGroup(elems[0]) {
+			buckets = append(buckets, &bucket{alt: a})
+			continue
+		}
+		key := elems[0].String()
+		b, ok := heads[key]
+		if !ok {
+			b = &bucket{head: elems[0], alt: a}
+			heads[key] = b
+			buckets = append(buckets, b)
+		}
+		b.rest = append(b.rest, cat(elems[1:]...))
+	}
+	var result []Pattern
+	for _, b := range buckets {
+		if len(b.rest) <= 1 {
+			result = append(result, b.alt)
+		} else {
+			result = append(result, cat(b.head, simplifyChoice(b.rest)))
+		}
+	}
+	return result
+}
+
+// mergeClasses merges all characters and character sets without named classes
+// among the alternatives into a single set, in place of the first one.
+func mergeClasses(alts []Pattern) []Pattern {
+	var result []Pattern
+	var merged spanSet
+	first, count := -1, 0
+	for _, a := range alts {
+		if spans := plainSpans(a); spans != nil {
+			if first == -1 {
+				first = len(result)
+				result = append(result, a)
+			}
+			merged = append(merged, spans...)
+			count++
+		} else {
+			result = append(result, a)
+		}
+	}
+	if count > 1 {
+		result[first] = &charSet{mod: &modifier{}, span: merged.compact()}
+	}
+	return result
+}
+
+// fold appends the pattern to the sequence, combining it with the patterns before
+// it: x x* and x* x become x+, x* x* becomes x*, and x* x+ and x+ x* become x+. The
+// repeated pattern x can be a sequence in x x*.
+func fold(seq []Pattern, p Pattern) []Pattern {
+	if hasGroup(p) || len(seq) == 0 {
+		return append(seq, p)
+	}
+	last := seq[len(seq)-1]
+	re, closure := repeated(p)
+	if closure != nil {
+		if lastRe, lastClosure := repeated(last); lastClosure != nil && lastRe.String() == re.String() {
+			if _, ok := lastClosure.(*zeroOrMore); ok {
+				seq[len(seq)-1] = p
+				return seq
+			} else if _, ok := closure.(*zeroOrMore); ok {
+				return seq
+			}
+		}
+		if _, ok := closure.(*zeroOrMore); ok {
+			n := len(elements(re))
+			if n <= len(seq) && cat(seq[len(seq)-n:]...).String() == re.String() {
+				return append(seq[:len(seq)-n], &oneOrMore{re})
+			}
+		}
+	}
+	if lastRe, lastClosure := repeated(last); lastClosure != nil && lastRe.String() == p.String() {
+		if _, ok := lastClosure.(*zeroOrMore); ok {
+			seq[len(seq)-1] = &oneOrMore{lastRe}
+			return seq
+		}
+	}
+	return append(seq, p)
+}
+
+// repeated returns the pattern repeated by a closure (x* or x+) and the closure, or
+// nil if p is not a closure.
+func repeated(p Pattern) (Pattern, Pattern) {
+	switch p := p.(type) {
+	case *zeroOrMore:
+		return p.re, p
+	case *oneOrMore:
+		return p.re, p
+	}
+	return nil, nil
+}
+
+// alt returns the choice between two patterns, either of which can be nil for no
+// pattern. Character sets are combined and a choice with the empty string becomes
+// optional.
+func alt(a, b Pattern) Pattern {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.String() == b.String():
+		return a
+	case isEpsilon(a):
+		return optional(b)
+	case isEpsilon(b):
+		return optional(a)
+	}
+	if x, y := plainSpans(a), plainSpans(b); x != nil && y != nil {
+		return &charSet{mod: &modifier{}, span: append(append(spanSet{}, x...), y...).compact()}
+	}
+	return &choice{a, b}
+}
+
+// choiceOf returns the choice between the alternatives, or nil if there are none.
+func choiceOf(alts []Pattern) Pattern {
+	if len(alts) == 0 {
+		return nil
+	}
+	p := alts[len(alts)-1]
+	for i := len(alts) - 2; i >= 0; i-- {
+		p = &choice{alts[i], p}
+	}
+	return p
+}
+
+// optional returns the pattern matching the pattern or the empty string.
+func optional(p Pattern) Pattern {
+	switch p := p.(type) {
+	case *zeroOrOne, *zeroOrMore:
+		return p
+	case *oneOrMore:
+		return &zeroOrMore{p.re}
+	}
+	if isEpsilon(p) {
+		return p
+	}
+	return &zeroOrOne{p}
+}
+
+// star returns the Kleene closure of the pattern, or the empty sequence if the
+// pattern is nil.
+func star(p Pattern) Pattern {
+	switch p := p.(type) {
+	case nil:
+		return &sequence{}
+	case *zeroOrMore:
+		return p
+	case *zeroOrOne:
+		return &zeroOrMore{p.opt}
+	case *oneOrMore:
+		return &zeroOrMore{p.re}
+	}
+	if isEpsilon(p) {
+		return p
+	}
+	return &zeroOrMore{p}
+}
+
+// plus returns the positive closure of the pattern.
+func plus(p Pattern) Pattern {
+	switch p := p.(type) {
+	case *zeroOrMore, *oneOrMore:
+		return p
+	case *zeroOrOne:
+		return &zeroOrMore{p.opt}
+	}
+	if isEpsilon(p) {
+		return p
+	}
+	return &oneOrMore{p}
+}
+
+// cat returns the sequence of the patterns, flattening nested sequences. A single
+// pattern is returned as is.
+func cat(patterns ...Pattern) Pattern {
+	var s []Pattern
+	for _, p := range patterns {
+		s = append(s, elements(p)...)
+	}
+	if len(s) == 1 {
+		return s[0]
+	}
+	return &sequence{s}
+}
+
+// elements returns the patterns of a sequence, or the pattern itself if it is not a sequence.
+func elements(p Pattern) []Pattern {
+	if s, ok := p.(*sequence); ok {
+		return s.sequence
+	}
+	return []Pattern{p}
+}
+
+// alternatives returns the alternatives of nested choices, or the pattern itself if
+// it is not a choice.
+func alternatives(p Pattern) []Pattern {
+	if c, ok := p.(*choice); ok {
+		return append(alternatives(c.left), alternatives(c.right)...)
+	}
+	return []Pattern{p}
+}
+
+// isEpsilon returns true if the pattern is the empty sequence, which matches the empty string.
+func isEpsilon(p Pattern) bool {
+	s, ok := p.(*sequence)
+	return ok && len(s.sequence) == 0
+}
+
+// plainSpans returns the characters matched by a character, a range or a character set
+// without named classes, or nil for any other pattern.
+func plainSpans(p Pattern) spanSet {
+	switch c := p.(type) {
+	case *singleChar, *charRange:
+		return c.(char).spanSet()
+	case *charSet:
+		if c.plain() {
+			return c.spanSet()
+		}
+	}
+	return nil
+}
+
+// hasGroup returns true if the pattern contains a capturing group.
+func hasGroup(p Pattern) bool {
+	found := false
+	Walk(p, func(n Node) bool {
+		found = found || n.Op == OpGroup
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,160 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"testing"
+)
+
+func TestCanonicalString(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"[a-z0-9_]", "[0-9_a-z]"},
+		{"[cba]", "[a-c]"},
+		{"[ab]", "[ab]"},
+		{"[^a]", "[^a]"},
+		{"[\\]\\-]", "[\\-\\]]"},
+		{"\\d{4}", "[0-9]{4}"},
+		{"\\w+", "[0-9A-Z_a-z]+"},
+		{"[\\d.]", "[.0-9]"},
+		{"[[:alpha:]_]", "[[:alpha:]_]"},
+		{"[\\p{Greek}\\d]", "[\\p{Greek}0-9]"},
+		{"\\p{Lu}", "\\p{Lu}"},
+		{"(?i)ab", "[Aa][Bb]"},
+		{"(?i)1", "1"},
+		{"(?s).", "(?s:.)"},
+		{"(?m)^a$", "(?m:^)a(?m:$)"},
+		{"a{0,}", "a*"},
+		{"a{1,}", "a+"},
+		{"a{0,1}", "a?"},
+		{"a{3,3}", "a{3}"},
+		{"a{,3}", "a{0,3}"},
+		{"(?:ab)*", "(?:ab)*"},
+		{"(?:a|b)c", "(?:a|b)c"},
+		{"(a|b)c", "(a|b)c"},
+		{"(?<x>a)", "(?<x>a)"},
+		{"(:word_en:ls)", "(:word_en:ls)"},
+		{"a\\.b\\*", "a\\.b\\*"},
+		{"\\n\\t\\x01", "\\n\\t\\x01"},
+	}
+	for _, test := range tests {
+		r := MustCompile(test.pattern)
+		if s := r.String(); s != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.pattern, test.expected, s)
+		}
+		if back := MustCompile(r.String()); back.String() != r.String() {
+			t.Errorf("%q: %q is written %q when parsed", test.pattern, r.String(), back.String())
+		}
+	}
+}
+
+func TestCanonicalRoundTrip(t *testing.T) {
+	tests := []string{
+		"[a-z0-9_]+@[a-z]+\\.(com|org)",
+		"(?i)select\\s+\\*\\s+from",
+		"[^\\s\\d]+",
+		"(?s)a.*b",
+		"\\bword\\b",
+		"[[:^digit:]x]",
+		"\\P{L}+",
+		"(?i)\\p{Lu}",
+		"[\\^\\\\]",
+		"(a{2}){3}",
+		"(?m)^\\d+$",
+		"x|",
+		"(?:)*a",
+	}
+	for _, test := range tests {
+		r := MustCompile(test)
+		back, err := Compile(r.String())
+		if err != nil {
+			t.Errorf("%q: %q does not compile: %v", test, r.String(), err)
+			continue
+		}
+		if equivalent, s := Equivalent(r, back); !equivalent {
+			t.Errorf("%q: %q differs on %q", test, r.String(), s)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"a|b|c", "[a-c]"},
+		{"a|[bc]|\\d", "[0-9a-c]"},
+		{"a|b|xy", "[ab]|xy"},
+		{"abc|abd", "ab[cd]"},
+		{"ab|abc", "abc?"},
+		{"if|in|int", "i(?:f|nt?)"},
+		{"aa*", "a+"},
+		{"a*a", "a+"},
+		{"a*a*", "a*"},
+		{"a+a*", "a+"},
+		{"a*a+", "a+"},
+		{"a+a+", "a+a+"},
+		{"ab(?:ab)*", "(?:ab)+"},
+		{"[a-z][a-z]*", "[a-z]+"},
+		{"(?:a*)?", "a*"},
+		{"(?:a+)*", "a*"},
+		{"(?:a?)+", "a*"},
+		{"a{1}", "a"},
+		{"a{0,1}", "a?"},
+		{"a{1,}", "a+"},
+		{"x(?:)y", "xy"},
+		{"a|a", "a"},
+		{"a||b", "[ab]?"},
+		{"(a)|(a)", "(a)|(a)"},
+		{"(a)(a)*", "(a)(a)*"},
+		{"(a)b|(a)c", "(a)b|(a)c"},
+		{"x(?:(a)|b|c)", "x(?:(a)|[bc])"},
+	}
+	for _, test := range tests {
+		r := MustCompile(test.pattern)
+		s := Simplify(r.Pattern)
+		if s.String() != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.pattern, test.expected, s.String())
+		}
+		if equivalent, c := Equivalent(r, NewRegexFromPattern(s)); !equivalent {
+			t.Errorf("%q: simplified %q differs on %q", test.pattern, s.String(), c)
+		}
+	}
+}
+
+func TestSimplifyMembership(t *testing.T) {
+	// all the strings of a, b and c of at most 4 characters
+	inputs := []string{""}
+	for i := 0; i < len(inputs) && len(inputs[i]) < 4; i++ {
+		for _, c := range "abc" {
+			inputs = append(inputs, inputs[i]+string(c))
+		}
+	}
+	tests := []struct {
+		pattern  string
+		rejected string
+	}{
+		{"ab*|", "b"},
+		{"|ab*", "bb"},
+		{"ab*|c", "b"},
+		{"(?:ab*)?c", "bc"},
+		{"a(?:b|bc)*|", "bc"},
+		{"(?:a|ab)*b?", "ba"},
+		{"ab|abc|", "bc"},
+	}
+	for _, test := range tests {
+		r := MustCompile(test.pattern)
+		s := Simplify(r.Pattern)
+		simplified, parsed := NewRegexFromPattern(s), MustCompile(s.String())
+		if simplified.Match(test.rejected) || parsed.Match(test.rejected) {
+			t.Errorf("%q: simplified %q matches %q", test.pattern, s.String(), test.rejected)
+		}
+		for _, input := range inputs {
+			if m := r.Match(input); simplified.Match(input) != m || parsed.Match(input) != m {
+				t.Errorf("%q: simplified %q differs on %q", test.pattern, s.String(), input)
+			}
+		}
+	}
+}
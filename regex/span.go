@@ -3,6 +3,7 @@ package regex
 import (
 	"math/rand"
 	"slices"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -17,15 +18,20 @@ type (
 
 var (
 	allUnicode     = spanSet{span{0, utf8.MaxRune}}
+	allButNewline  = spanSet{span{0, '\n' - 1}, span{'\n' + 1, utf8.MaxRune}}
 	asciiPrintable = spanSet{span{32, 126}}
+
+	// surrogates are the halves of UTF-16 surrogate pairs, which are not valid characters
+	// on their own and cannot be encoded in UTF-8.
+	surrogates = spanSet{span{0xD800, 0xDFFF}}
 )
 
 func (r span) len() int {
 	return int(r.to) - int(r.from) + 1
 }
 
-func (r span) random() rune {
-	return rune(int(r.from) + rand.Intn(r.len()))
+func (r span) random(rng *rand.Rand) rune {
+	return rune(int(r.from) + intn(rng, r.len()))
 }
 
 func (r span) intersect(other span) bool {
@@ -36,6 +42,15 @@ func (r span) match(c rune) bool {
 	return r.from <= c && c <= r.to
 }
 
+// intn returns a random number in [0, n) from the random number generator, or from
+// the default source of math/rand if the generator is nil.
+func intn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
+	}
+	return rng.Intn(n)
+}
+
 func (r spanSet) len() int {
 	l := 0
 	for _, s := range r {
@@ -44,12 +59,12 @@ func (r spanSet) len() int {
 	return l
 }
 
-func (r spanSet) random() rune {
-	n := rand.Intn(r.len())
+func (r spanSet) random(rng *rand.Rand) rune {
+	n := intn(rng, r.len())
 	for _, s := range r {
 		count := s.len()
 		if n < count {
-			return s.random()
+			return s.random(rng)
 		}
 		n -= count
 	}
@@ -83,10 +98,11 @@ func (r spanSet) minus(other spanSet) spanSet {
 					result = append(result, span{left.from, r2[j].from - 1})
 				}
 				if left.to <= r2[j].to {
+					// r2[j] can also cover the next spans of r1
 					reachedEnd = true
-				} else {
-					left.from = r2[j].to + 1
+					break
 				}
+				left.from = r2[j].to + 1
 				j++
 			}
 			if !reachedEnd {
@@ -98,6 +114,43 @@ func (r spanSet) minus(other spanSet) spanSet {
 	return result
 }
 
+func (r spanSet) intersection(other spanSet) spanSet {
+	return r.minus(r.minus(other))
+}
+
+// String returns the spans as a character set in the syntax of regular expressions,
+// which is negated if it includes the last Unicode character, as in [^a-z].
+func (r spanSet) String() string {
+	if len(r) == 0 {
+		// the complement of all characters, as an empty set is not valid
+		return "[^\\x00-\\x{10ffff}]"
+	}
+	spans := slices.Clone(r).compact()
+	if n := len(spans); spans[n-1].to == utf8.MaxRune {
+		if inverted := spans.invertUnicode(); len(inverted) > 0 {
+			return "[^" + inverted.content() + "]"
+		}
+	}
+	return "[" + spans.content() + "]"
+}
+
+// content returns the sorted spans as the content of a character set, with the
+// characters escaped as required in sets.
+func (r spanSet) content() string {
+	var s strings.Builder
+	for _, sp := range r {
+		s.WriteString(escapeChar(sp.from, true))
+		if sp.to > sp.from+1 {
+			s.WriteRune('-')
+		}
+		if sp.to > sp.from {
+			s.WriteString(escapeChar(sp.to, true))
+		}
+	}
+	return s.String()
+}
+
+// compact returns the spans sorted, with overlapping and adjacent spans merged.
 func (r spanSet) compact() spanSet {
 	if len(r) <= 1 {
 		return r[:]
@@ -106,7 +159,7 @@ func (r spanSet) compact() spanSet {
 	result := spanSet{r[0]}
 	for i := 1; i < len(r); i++ {
 		last := &result[len(result)-1]
-		if last.intersect(r[i]) {
+		if last.intersect(r[i]) || last.to+1 == r[i].from {
 			if last.to < r[i].to {
 				last.to = r[i].to
 			}
@@ -148,3 +201,12 @@ func (r spanSet) match(c rune) bool {
 	}
 	return false
 }
+
+// representative returns a character of the spans, which is the first printable ASCII
+// character if there is one, and the first character otherwise.
+func (r spanSet) representative() rune {
+	if printable := r.intersection(asciiPrintable); len(printable) > 0 {
+		return printable[0].from
+	}
+	return r[0].from
+}
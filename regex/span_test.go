@@ -117,3 +117,40 @@ func TestMinus2(t *testing.T) {
 		t.Error("expected", s4, "actual", s3)
 	}
 }
+
+func TestMinusSpans(t *testing.T) {
+	tests := []struct {
+		left, right, expected spanSet
+	}{
+		{spanSet{{0, 10}}, spanSet{{2, 3}, {5, 6}}, spanSet{{0, 1}, {4, 4}, {7, 10}}},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{0, 20}}, nil},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{1, 9}}, spanSet{{0, 0}, {10, 10}}},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{2, 4}, {6, 8}}, spanSet{{0, 1}, {5, 5}, {9, 10}}},
+		{spanSet{{9, 13}, {32, 32}}, spanSet{{0, 47}, {58, 64}}, nil},
+		{spanSet{{9, 13}, {32, 32}, {160, 160}}, spanSet{{0, 47}, {58, 64}}, spanSet{{160, 160}}},
+		{spanSet{{0, 5}, {10, 15}}, spanSet{{20, 30}}, spanSet{{0, 5}, {10, 15}}},
+	}
+	for _, test := range tests {
+		if actual := test.left.minus(test.right); !slices.Equal(actual, test.expected) {
+			t.Error(test.left, "-", test.right, ": expected", test.expected, "actual", actual)
+		}
+	}
+}
+
+func TestIntersection(t *testing.T) {
+	tests := []struct {
+		left, right, expected spanSet
+	}{
+		{spanSet{{0, 10}}, spanSet{{2, 3}, {5, 6}}, spanSet{{2, 3}, {5, 6}}},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{0, 20}}, spanSet{{0, 2}, {4, 6}, {8, 10}}},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{1, 9}}, spanSet{{1, 2}, {4, 6}, {8, 9}}},
+		{spanSet{{0, 2}, {4, 6}, {8, 10}}, spanSet{{2, 4}, {6, 8}}, spanSet{{2, 2}, {4, 4}, {6, 6}, {8, 8}}},
+		{spanSet{{9, 13}, {32, 32}}, spanSet{{0, 47}, {58, 64}}, spanSet{{9, 13}, {32, 32}}},
+		{spanSet{{0, 5}, {10, 15}}, spanSet{{20, 30}}, nil},
+	}
+	for _, test := range tests {
+		if actual := test.left.intersection(test.right); !slices.Equal(actual, test.expected) {
+			t.Error(test.left, "&", test.right, ": expected", test.expected, "actual", actual)
+		}
+	}
+}
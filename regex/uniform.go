@@ -0,0 +1,131 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+// Strings are generated uniformly by counting the paths of the DFA. The number of
+// strings of length n accepted from a state is the sum, over the transitions leaving
+// the state, of the number of characters of the transition times the number of strings
+// of length n-1 accepted from its target, starting from 1 for final states and 0 for
+// the others at length 0. A string is then generated by choosing its length in
+// proportion to the number of strings of each length, then each transition in
+// proportion to the number of strings continuing through it, and finally a character
+// of the transition uniformly. Surrogate halves (U+D800 to U+DFFF), which sets such as
+// . and [^a] include but which are not valid characters, are neither counted nor
+// generated.
+//
+// Counting requires a DFA in which each character leads to a single state, so that
+// each string is counted once: the DFA of the regular expression is first converted
+// to such a DFA by a product construction with itself alone, then trimmed and minimized.
+
+import (
+	"math/big"
+	"math/rand"
+	"slices"
+)
+
+// UniformGenerator generates strings matched by a regular expression, uniformly among
+// all those with a length, in characters, in a given range. The words of word lists
+// are counted once each, whatever their weights.
+type UniformGenerator struct {
+	dfa    *automata
+	minLen int
+	maxLen int
+	chars  map[char]spanSet // characters of the transitions, without surrogate halves
+
+	// counts[n][s] is the number of strings of length n accepted from state s.
+	counts []map[state]*big.Int
+}
+
+// Uniform returns a generator of the strings matched by the regular expression with a
+// length between minLen and maxLen characters, inclusive.
+func (r *Regex) Uniform(minLen, maxLen int) *UniformGenerator {
+	minLen = max(minLen, 0)
+	maxLen = max(maxLen, minLen)
+	dfa := r.automaton().disjoint().trim().minimize()
+	chars := map[char]spanSet{}
+	for _, trans := range dfa.Trans {
+		for c := range trans {
+			chars[c] = slices.Clone(c.spanSet()).minus(surrogates)
+		}
+	}
+
+	counts := make([]map[state]*big.Int, maxLen+1)
+	counts[0] = map[state]*big.Int{}
+	for s := range dfa.finalMap {
+		counts[0][s] = big.NewInt(1)
+	}
+	for n := 1; n <= maxLen; n++ {
+		counts[n] = map[state]*big.Int{}
+		for s, trans := range dfa.Trans {
+			total := new(big.Int)
+			for c, t := range trans {
+				if next, ok := counts[n-1][t]; ok {
+					total.Add(total, new(big.Int).Mul(big.NewInt(int64(chars[c].len())), next))
+				}
+			}
+			if total.Sign() > 0 {
+				counts[n][s] = total
+			}
+		}
+	}
+	return &UniformGenerator{dfa, minLen, maxLen, chars, counts}
+}
+
+// Count returns the number of strings which can be generated, with a length in the
+// range of the generator.
+func (g *UniformGenerator) Count() *big.Int {
+	total := new(big.Int)
+	for n := g.minLen; n <= g.maxLen; n++ {
+		if count, ok := g.counts[n][g.dfa.start]; ok {
+			total.Add(total, count)
+		}
+	}
+	return total
+}
+
+// Generate returns a string chosen uniformly among those which can be generated, using
+// the random number generator, or the default source of math/rand if it is nil. It
+// returns false if there is no string with a length in the range of the generator.
+func (g *UniformGenerator) Generate(rng *rand.Rand) (string, bool) {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	total := g.Count()
+	if total.Sign() == 0 {
+		return "", false
+	}
+
+	// choose the length in proportion to the number of strings of each length
+	pick := new(big.Int).Rand(rng, total)
+	length := g.minLen
+	for ; length < g.maxLen; length++ {
+		if count, ok := g.counts[length][g.dfa.start]; ok {
+			if pick.Cmp(count) < 0 {
+				break
+			}
+			pick.Sub(pick, count)
+		}
+	}
+
+	// choose each transition in proportion to the number of strings through it
+	s := []rune{}
+	state := g.dfa.start
+	for remaining := length; remaining > 0; remaining-- {
+		pick := new(big.Int).Rand(rng, g.counts[remaining][state])
+		for _, t := range g.dfa.sortedTransitions(state) {
+			next, ok := g.counts[remaining-1][t.to]
+			if !ok {
+				continue
+			}
+			spans := g.chars[t.char]
+			count := new(big.Int).Mul(big.NewInt(int64(spans.len())), next)
+			if pick.Cmp(count) < 0 {
+				s = append(s, spans.random(rng))
+				state = t.to
+				break
+			}
+			pick.Sub(pick, count)
+		}
+	}
+	return string(s), true
+}
@@ -0,0 +1,97 @@
+// author: Vikash Madhow (vikash.madhow@gmail.com)
+
+package regex
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUniformCount(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		min, max int
+		expected int64
+	}{
+		{"[ab]*", 0, 3, 15},
+		{"[ab]*", 2, 2, 4},
+		{"a|b|ab", 0, 5, 3},
+		{"a|b|ab", 2, 5, 1},
+		{"[a-z]+|if", 2, 2, 676},
+		{"[0-9]{3}", 0, 2, 0},
+		{"x(ab)*", 1, 6, 3},
+		{"\\bab\\b", 0, 4, 1},
+		{"(?i)k", 1, 1, 2},
+		{"(?s).", 1, 1, utf8.MaxRune + 1 - 0x800},
+		{"[\\x{D000}-\\x{DFFF}]", 1, 1, 0x800},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			g := NewRegex(test.pattern).Uniform(test.min, test.max)
+			if count := g.Count(); count.Cmp(big.NewInt(test.expected)) != 0 {
+				t.Errorf("Count() = %v, expected %v", count, test.expected)
+			}
+		})
+	}
+}
+
+func TestUniformGenerate(t *testing.T) {
+	r := NewRegex("a|[b-z][a-z]{0,2}")
+	g := r.Uniform(1, 3)
+	rng := rand.New(rand.NewSource(1))
+	lengths := map[int]int{}
+	const samples = 20000
+	for i := 0; i < samples; i++ {
+		s, ok := g.Generate(rng)
+		if !ok {
+			t.Fatal("no string generated")
+		}
+		if !r.Match(s) {
+			t.Fatalf("%q does not match", s)
+		}
+		lengths[utf8.RuneCountInString(s)]++
+	}
+
+	// 1 + 25 strings of 1 character, 25*26 of 2 and 25*26*26 of 3
+	total := 1.0 + 25 + 25*26 + 25*26*26
+	for n, count := range map[int]float64{1: 26, 2: 25 * 26, 3: 25 * 26 * 26} {
+		expected := count / total
+		if got := float64(lengths[n]) / samples; math.Abs(got-expected) > 0.02 {
+			t.Errorf("proportion of strings of length %d is %.3f, expected %.3f", n, got, expected)
+		}
+	}
+}
+
+func TestUniformGenerateSurrogates(t *testing.T) {
+	g := NewRegex("[\\x{D7FF}-\\x{E000}]").Uniform(1, 1)
+	if count := g.Count(); count.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("Count() = %v, expected 2", count)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for range 20 {
+		if s, _ := g.Generate(rng); s != "\uD7FF" && s != "\uE000" {
+			t.Errorf("generated %q", s)
+		}
+	}
+}
+
+func TestUniformGenerateReproducible(t *testing.T) {
+	g := NewRegex("[A-Z]{2}[0-9]{1,4}").Uniform(3, 6)
+	first, second := rand.New(rand.NewSource(3)), rand.New(rand.NewSource(3))
+	for i := 0; i < 20; i++ {
+		a, _ := g.Generate(first)
+		b, _ := g.Generate(second)
+		if a != b {
+			t.Fatalf("different strings generated with the same seed: %q and %q", a, b)
+		}
+	}
+}
+
+func TestUniformGenerateNone(t *testing.T) {
+	if s, ok := NewRegex("[0-9]{3}").Uniform(4, 10).Generate(nil); ok {
+		t.Errorf("%q generated, expected none", s)
+	}
+}